@@ -2,4 +2,9 @@ module github.com/zhangymPerson/dev-env-manage
 
 go 1.25.1
 
-require github.com/mattn/go-sqlite3 v1.14.32
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/mattn/go-sqlite3 v1.14.32
+)
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -3,11 +3,8 @@ package main
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 
 	dem "github.com/zhangymPerson/dev-env-manage/src"
-	"github.com/zhangymPerson/dev-env-manage/src/constant"
-	"github.com/zhangymPerson/dev-env-manage/src/db"
 	"github.com/zhangymPerson/dev-env-manage/src/log"
 )
 
@@ -20,16 +17,7 @@ var (
 )
 
 func main() {
-	// 最先配置日志
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		panic(err)
-	}
-	logFile := filepath.Join(homeDir, ".dem", "dem.log")
-	if err := log.Configure(false, logFile); err != nil {
-		panic(fmt.Sprintf("Failed to configure logger: %v", err))
-	}
-
+	// 日志、数据库等初始化依赖设置文件，在 Options 解析参数后进行
 	// 确保在程序结束时关闭日志文件
 	defer func() {
 		if err := log.Close(); err != nil {
@@ -37,8 +25,5 @@ func main() {
 		}
 	}()
 
-	// 然后执行其他初始化
-	dem.Init()
-	db.InitDB(constant.GetDBFilePath())
 	dem.Options(GitBranch, GitCommit)
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"time"
@@ -12,17 +13,46 @@ import (
 )
 
 // HandleAddCommand handles the add command
-func HandleAddCommand(project, env, module string, key, alias, value string) {
-	log.Info("key: %s, value: %s, alias: %s", key, value, alias)
-	if len(os.Args) < 4 {
-		log.Fatal("Usage: dem add <key> <value> [alias]")
-	}
-	if len(os.Args) > 4 {
-		alias = os.Args[4]
-	} else {
+// operator 为执行本次变更的操作者，记录到 updated_by 和历史记录的 changed_by
+// configType 为空时沿用已有配置的类型（新配置为 string），值与类型不符时拒绝写入
+// project/module 声明了该键的约束（dem schema apply）时，类型以约束为准，不符合约束的值同样拒绝写入；
+// 引用无法展开、无法按约束检查的值仅在 force 为 true 时写入
+func HandleAddCommand(project, env, module string, key, alias, value, configType string, force bool, operator string) {
+	log.Info("key: %s, alias: %s", key, alias)
+	if alias == "" {
 		alias = generateDefaultAlias(key)
 	}
 
+	rule, err := schemaRule(project, module, key)
+	if err != nil {
+		log.Fatalf("Failed to query schema of %s: %v", key, err)
+	}
+	var description *string
+	if rule != nil {
+		if configType != "" && configType != rule.ConfigType {
+			fmt.Fprintf(os.Stderr, "Invalid type for %s: the schema of %s/%s declares %s\n", key, project, module, rule.ConfigType)
+			os.Exit(1)
+		}
+		configType = rule.ConfigType
+		description = rule.Description
+	}
+
+	if configType == "" {
+		configType = TypeString
+		existing, err := queryMaster([]string{"project=?", "env=?", "module=?", "config_key=?"}, []interface{}{project, env, module, key})
+		if err != nil {
+			log.Fatalf("Failed to query %s: %v", key, err)
+		}
+		if len(existing) > 0 && constant.SafeStr(existing[0].ConfigType) != "" {
+			configType = constant.SafeStr(existing[0].ConfigType)
+		}
+	}
+	value, err = checkNewValue(project, env, module, key, configType, value, rule, force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid value for %s (%s): %v\n", key, configType, err)
+		os.Exit(1)
+	}
+
 	currentTime := time.Now()
 
 	// Create config using the updated ConfigMaster struct
@@ -34,12 +64,13 @@ func HandleAddCommand(project, env, module string, key, alias, value string) {
 		ConfigValue: constant.ToStrPtr(value),
 		ConfigAlias: constant.ToStrPtr(alias),
 		AutoAlias:   constant.ToStrPtr(generateDefaultAlias(key)),
-		ConfigType:  constant.ToStrPtr("string"),
+		ConfigType:  constant.ToStrPtr(configType),
 		IsEncrypted: constant.ToIntPtr(1),
-		Description: nil, // Set to nil or provide a value if needed
+		Description: description,
 		SortOrder:   nil, // Set to nil or provide a value if needed
 		CreatedTime: constant.ToTimePtr(currentTime),
 		UpdatedTime: constant.ToTimePtr(currentTime),
+		UpdatedBy:   constant.ToStrPtr(operator),
 	}
 
 	if err := db.AddConfig(config); err != nil {
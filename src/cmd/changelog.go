@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+)
+
+// LogFilter dem log 的过滤条件，空值表示不过滤
+type LogFilter struct {
+	Project   string
+	Env       string
+	Module    string
+	ChangedBy string
+	Since     string
+	Until     string
+	Limit     int
+}
+
+// logEntry 全局变更流中的一条记录，不包含配置值
+type logEntry struct {
+	ID         int64      `json:"id"`
+	Version    *time.Time `json:"version,omitempty"`
+	ChangedBy  *string    `json:"changed_by,omitempty"`
+	ChangeType *string    `json:"change_type,omitempty"`
+	Project    *string    `json:"project,omitempty"`
+	Env        *string    `json:"env,omitempty"`
+	Module     *string    `json:"module,omitempty"`
+	ConfigKey  *string    `json:"config_key,omitempty"`
+}
+
+// HandleLogCommand 按时间倒序显示所有项目的变更记录
+// follow 为 true 时按时间正序输出最近的记录，并每隔 interval 轮询新的变更；
+// 此时仅支持 text 与 json 输出，json 为每行一个对象（JSON Lines）
+func HandleLogCommand(filter LogFilter, follow bool, interval time.Duration) {
+	if follow && structuredOutput() && outputFormat != OutputJSON {
+		fmt.Fprintf(os.Stderr, "--follow supports --output text or json\n")
+		os.Exit(1)
+	}
+	var err error
+	if filter.Since != "" {
+		if filter.Since, err = parseTimeArg(filter.Since); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if filter.Until != "" {
+		if filter.Until, err = parseTimeArg(filter.Until); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	conditions, params := scopeConditions(filter.Project, filter.Env, filter.Module)
+	if filter.ChangedBy != "" {
+		conditions = append(conditions, "changed_by=?")
+		params = append(params, filter.ChangedBy)
+	}
+	if filter.Since != "" {
+		conditions = append(conditions, "version >= ?")
+		params = append(params, filter.Since)
+	}
+	if filter.Until != "" {
+		conditions = append(conditions, "version <= ?")
+		params = append(params, filter.Until)
+	}
+
+	entries, err := queryLog(conditions, params, "ORDER BY version DESC, id DESC", filter.Limit)
+	if err != nil {
+		log.Fatalf("Failed to query change log: %v", err)
+	}
+
+	if !follow {
+		if structuredOutput() {
+			if entries == nil {
+				entries = []logEntry{}
+			}
+			printStructured(entries, entries, logOutputColumns)
+			return
+		}
+		if len(entries) == 0 {
+			fmt.Println("No changes found.")
+			return
+		}
+		printLogEntries(entries)
+		return
+	}
+
+	// follow 模式：先按时间正序输出已有记录，再持续输出新记录
+	var lastID int64
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	for _, e := range entries {
+		lastID = max(lastID, e.ID)
+	}
+	if lastID == 0 {
+		if err := db.DB.QueryRow("SELECT IFNULL(MAX(id), 0) FROM config_history").Scan(&lastID); err != nil {
+			log.Fatalf("Failed to query change log: %v", err)
+		}
+	}
+	printLogEntries(entries)
+
+	for {
+		time.Sleep(interval)
+		entries, err := queryLog(append(conditions, "id > ?"), append(params, lastID), "ORDER BY id ASC", 0)
+		if err != nil {
+			log.Fatalf("Failed to query change log: %v", err)
+		}
+		for _, e := range entries {
+			lastID = max(lastID, e.ID)
+		}
+		printLogEntries(entries)
+	}
+}
+
+func queryLog(conditions []string, params []interface{}, order string, limit int) ([]logEntry, error) {
+	query := "SELECT id, version, changed_by, change_type, project, env, module, config_key FROM config_history"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " " + order
+	if limit > 0 {
+		query += " LIMIT ?"
+		params = append(append([]interface{}{}, params...), limit)
+	}
+
+	rows, err := db.DB.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []logEntry
+	for rows.Next() {
+		var e logEntry
+		if err := rows.Scan(&e.ID, &e.Version, &e.ChangedBy, &e.ChangeType, &e.Project, &e.Env, &e.Module, &e.ConfigKey); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// log 结构化输出时 table/csv 的列
+var logOutputColumns = []string{"id", "version", "changed_by", "change_type", "project", "env", "module", "config_key"}
+
+func printLogEntries(entries []logEntry) {
+	if structuredOutput() {
+		for _, e := range entries {
+			encoded, err := json.Marshal(e)
+			if err != nil {
+				log.Fatalf("Failed to encode change log: %v", err)
+			}
+			fmt.Println(string(encoded))
+		}
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s/%s/%s\t%s\n",
+			formatTime(e.Version), constant.SafeStr(e.ChangedBy), constant.SafeStr(e.ChangeType),
+			constant.SafeStr(e.Project), constant.SafeStr(e.Env), constant.SafeStr(e.Module),
+			constant.SafeStr(e.ConfigKey))
+	}
+	w.Flush()
+}
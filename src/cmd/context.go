@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/settings"
+)
+
+// contextResult context show 的结构化输出，context_file 为空表示没有上下文文件
+type contextResult struct {
+	ContextFile string `json:"context_file"`
+	Project     string `json:"project"`
+	Env         string `json:"env"`
+	Module      string `json:"module"`
+}
+
+// HandleContextShowCommand 显示当前目录生效的上下文文件以及最终使用的作用域
+func HandleContextShowCommand(project, env, module string) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Fatalf("Failed to get working directory: %v", err)
+	}
+	ctx, err := settings.FindContext(cwd)
+	if err != nil {
+		log.Fatalf("Failed to read context file: %v", err)
+	}
+
+	if structuredOutput() {
+		result := contextResult{Project: project, Env: env, Module: module}
+		if ctx != nil {
+			result.ContextFile = ctx.Path
+		}
+		printStructured(result, result, []string{"context_file", "project", "env", "module"})
+		return
+	}
+
+	if ctx == nil {
+		fmt.Printf("Context file: none (create one with 'dem context set')\n")
+	} else {
+		fmt.Printf("Context file: %s\n", ctx.Path)
+	}
+	fmt.Printf("Project: %s\n", project)
+	fmt.Printf("Env: %s\n", env)
+	fmt.Printf("Module: %s\n", module)
+}
+
+// HandleContextSetCommand 在当前目录创建或更新 .dem 上下文文件，空值表示保留原有设置
+func HandleContextSetCommand(project, env, module string) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Fatalf("Failed to get working directory: %v", err)
+	}
+	path := filepath.Join(cwd, constant.ContextFileName)
+
+	ctx := &settings.Context{Path: path}
+	if _, err := os.Stat(path); err == nil {
+		if ctx, err = settings.LoadContext(path); err != nil {
+			log.Fatalf("Failed to read context file: %v", err)
+		}
+	}
+	if project != "" {
+		ctx.Project = project
+	}
+	if env != "" {
+		ctx.Env = env
+	}
+	if module != "" {
+		ctx.Module = module
+	}
+
+	if err := ctx.Save(); err != nil {
+		log.Fatalf("Failed to write context file: %v", err)
+	}
+	fmt.Printf("Context saved to %s\n", path)
+	fmt.Printf("Project: %s\n", ctx.Project)
+	fmt.Printf("Env: %s\n", ctx.Env)
+	fmt.Printf("Module: %s\n", ctx.Module)
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/zhangymPerson/dev-env-manage/src/db"
 )
 
-func HandleDeleteCommand(project, env, module string, verbose bool, key string) {
+func HandleDeleteCommand(project, env, module string, verbose bool, key, operator string) {
 
 	// 首先检查配置项是否存在
 	var configID int
@@ -57,41 +57,9 @@ func HandleDeleteCommand(project, env, module string, verbose bool, key string)
 		return
 	}
 
-	// 执行物理删除
-	tx, err := db.DB.Begin()
-	if err != nil {
-		log.Fatalf("Failed to begin transaction: %v", err)
-	}
-
-	stmt, err := tx.Prepare(`
-		DELETE FROM config_master 
-		WHERE id = ?`)
-	if err != nil {
-		tx.Rollback()
-		log.Fatalf("Failed to prepare delete statement: %v", err)
-	}
-	defer stmt.Close()
-
-	res, err := stmt.Exec(configID)
-	if err != nil {
-		tx.Rollback()
-		log.Fatalf("Failed to execute delete: %v", err)
-	}
-
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		tx.Rollback()
-		log.Fatalf("Failed to get rows affected: %v", err)
-	}
-
-	if rowsAffected == 0 {
-		tx.Rollback()
-		log.Fatalf("No configuration item was deleted")
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		log.Fatalf("Failed to commit transaction: %v", err)
+	// 执行物理删除，操作者由删除触发器写入历史记录
+	if err := db.DeleteConfig(configID, operator); err != nil {
+		log.Fatalf("Failed to delete config: %v", err)
 	}
 
 	if verbose {
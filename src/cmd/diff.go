@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+)
+
+// diff 中一个键的差异类型
+const (
+	DiffOnlyLeft  = "only_left"  // 仅左侧作用域存在
+	DiffOnlyRight = "only_right" // 仅右侧作用域存在
+	DiffChanged   = "changed"    // 两侧都存在，值或类型不同
+)
+
+// diffEntry 两个作用域中一个键的差异
+type diffEntry struct {
+	Key          string  `json:"config_key"`
+	Status       string  `json:"status"`
+	LeftValue    *string `json:"left_value,omitempty"`
+	RightValue   *string `json:"right_value,omitempty"`
+	LeftType     string  `json:"left_type,omitempty"`
+	RightType    string  `json:"right_type,omitempty"`
+	ValueDiffers bool    `json:"value_differs"`
+	TypeDiffers  bool    `json:"type_differs"`
+
+	left, right models.ConfigMaster // 库中的配置，不存在的一侧为零值
+}
+
+// diffResult diff 的结构化输出
+type diffResult struct {
+	Left        string      `json:"left"`
+	Right       string      `json:"right"`
+	Identical   bool        `json:"identical"`
+	Differences []diffEntry `json:"differences"`
+}
+
+// diff 结构化输出时 table/csv 的列
+var diffOutputColumns = []string{"config_key", "status", "left_value", "right_value", "left_type", "right_type"}
+
+// HandleDiffCommand 逐键比较两个作用域中保存的配置（不展开引用、不按作用域层回退），存在差异时以状态码 1 退出
+// left、right 的格式见 parseScopeArg；加密的值除非 reveal 为 true 否则以 ****** 显示
+func HandleDiffCommand(project, module, left, right string, reveal bool) {
+	leftScope, err := parseScopeArg(left, project, module)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	rightScope, err := parseScopeArg(right, project, module)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	entries, err := diffScopes(leftScope, rightScope, nil)
+	if err != nil {
+		log.Fatalf("Failed to compare %s and %s: %v", left, right, err)
+	}
+	if !reveal {
+		for i := range entries {
+			maskDiffEntry(&entries[i])
+		}
+	}
+
+	result := diffResult{
+		Left:        strings.Join(leftScope[:], "/"),
+		Right:       strings.Join(rightScope[:], "/"),
+		Identical:   len(entries) == 0,
+		Differences: entries,
+	}
+	if result.Differences == nil {
+		result.Differences = []diffEntry{}
+	}
+	if structuredOutput() {
+		printStructured(result, result.Differences, diffOutputColumns)
+	} else {
+		printDiff(result)
+	}
+	if !result.Identical {
+		os.Exit(1)
+	}
+}
+
+// parseScopeArg 解析命令行中的作用域，与引用 ${...} 的写法一致：
+//
+//	env                  使用 -p、-m 指定的 project、module
+//	env:module           使用 -p 指定的 project
+//	project:env:module
+func parseScopeArg(arg, project, module string) ([3]string, error) {
+	parts := strings.Split(arg, ":")
+	for _, part := range parts {
+		if part == "" {
+			return [3]string{}, fmt.Errorf("invalid scope %q, use env, env:module or project:env:module", arg)
+		}
+	}
+	switch len(parts) {
+	case 1:
+		return [3]string{project, parts[0], module}, nil
+	case 2:
+		return [3]string{project, parts[0], parts[1]}, nil
+	case 3:
+		return [3]string{parts[0], parts[1], parts[2]}, nil
+	}
+	return [3]string{}, fmt.Errorf("invalid scope %q, use env, env:module or project:env:module", arg)
+}
+
+// diffScopes 比较两个作用域中保存的配置，结果按键排序；match 不为 nil 时只比较其返回 true 的键
+func diffScopes(left, right [3]string, match func(key string) bool) ([]diffEntry, error) {
+	leftConfigs, err := queryMaster([]string{"project=?", "env=?", "module=?"}, []interface{}{left[0], left[1], left[2]})
+	if err != nil {
+		return nil, err
+	}
+	rightConfigs, err := queryMaster([]string{"project=?", "env=?", "module=?"}, []interface{}{right[0], right[1], right[2]})
+	if err != nil {
+		return nil, err
+	}
+
+	rightByKey := map[string]models.ConfigMaster{}
+	for _, c := range rightConfigs {
+		rightByKey[constant.SafeStr(c.ConfigKey)] = c
+	}
+	var entries []diffEntry
+	seen := map[string]bool{}
+	for _, l := range leftConfigs {
+		key := constant.SafeStr(l.ConfigKey)
+		seen[key] = true
+		if match != nil && !match(key) {
+			continue
+		}
+		r, ok := rightByKey[key]
+		if !ok {
+			entries = append(entries, diffEntry{Key: key, Status: DiffOnlyLeft, LeftValue: l.ConfigValue,
+				LeftType: constant.SafeStr(l.ConfigType), left: l})
+			continue
+		}
+		entry := diffEntry{Key: key, Status: DiffChanged,
+			LeftValue: l.ConfigValue, RightValue: r.ConfigValue,
+			LeftType: constant.SafeStr(l.ConfigType), RightType: constant.SafeStr(r.ConfigType),
+			ValueDiffers: constant.SafeStr(l.ConfigValue) != constant.SafeStr(r.ConfigValue),
+			TypeDiffers:  constant.SafeStr(l.ConfigType) != constant.SafeStr(r.ConfigType),
+			left:         l, right: r}
+		if entry.ValueDiffers || entry.TypeDiffers {
+			entries = append(entries, entry)
+		}
+	}
+	for _, r := range rightConfigs {
+		key := constant.SafeStr(r.ConfigKey)
+		if seen[key] || (match != nil && !match(key)) {
+			continue
+		}
+		entries = append(entries, diffEntry{Key: key, Status: DiffOnlyRight, RightValue: r.ConfigValue,
+			RightType: constant.SafeStr(r.ConfigType), right: r})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
+// maskDiffEntry 隐藏加密配置的值
+func maskDiffEntry(entry *diffEntry) {
+	if entry.LeftValue != nil && isEncrypted(entry.left) {
+		entry.LeftValue = constant.ToStrPtr(maskedValue)
+	}
+	if entry.RightValue != nil && isEncrypted(entry.right) {
+		entry.RightValue = constant.ToStrPtr(maskedValue)
+	}
+}
+
+func printDiff(result diffResult) {
+	if result.Identical {
+		fmt.Printf("No differences between %s and %s\n", result.Left, result.Right)
+		return
+	}
+	fmt.Println(colorize(colorRed, "--- "+result.Left))
+	fmt.Println(colorize(colorGreen, "+++ "+result.Right))
+	counts := map[string]int{}
+	for _, entry := range result.Differences {
+		counts[entry.Status]++
+		fmt.Println(formatDiffEntry(entry))
+	}
+	fmt.Printf("%d only in %s, %d only in %s, %d changed\n",
+		counts[DiffOnlyLeft], result.Left, counts[DiffOnlyRight], result.Right, counts[DiffChanged])
+}
+
+// formatDiffEntry 以一行带颜色的文本描述差异：- 仅左侧，+ 仅右侧，~ 两侧不同
+func formatDiffEntry(entry diffEntry) string {
+	switch entry.Status {
+	case DiffOnlyLeft:
+		return colorize(colorRed, fmt.Sprintf("- %s = %q", entry.Key, constant.SafeStr(entry.LeftValue)))
+	case DiffOnlyRight:
+		return colorize(colorGreen, fmt.Sprintf("+ %s = %q", entry.Key, constant.SafeStr(entry.RightValue)))
+	}
+	line := "~ " + entry.Key
+	if entry.ValueDiffers {
+		line += fmt.Sprintf(" = %q -> %q", constant.SafeStr(entry.LeftValue), constant.SafeStr(entry.RightValue))
+	}
+	if entry.TypeDiffers {
+		line += fmt.Sprintf(" (%s -> %s)", entry.LeftType, entry.RightType)
+	}
+	return colorize(colorYellow, line)
+}
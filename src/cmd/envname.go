@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+)
+
+// KeyMapper 将配置键映射为环境变量名
+// 默认规则：非字母数字字符替换为下划线并转为大写，如 database.host -> DATABASE_HOST
+type KeyMapper struct {
+	Prefix    string            // 添加到变量名前的前缀，如 APP_
+	Case      string            // upper（默认）、lower 或 keep
+	Overrides map[string]string // 按配置键指定的变量名，优先于其他规则
+}
+
+// Name 返回配置键对应的环境变量名
+func (m KeyMapper) Name(key string) string {
+	if name, ok := m.Overrides[key]; ok {
+		return name
+	}
+	var b strings.Builder
+	for _, r := range key {
+		if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+	switch m.Case {
+	case "lower":
+		name = strings.ToLower(name)
+	case "keep":
+	default:
+		name = strings.ToUpper(name)
+	}
+	name = m.Prefix + name
+	// 变量名不能以数字开头
+	if name != "" && '0' <= name[0] && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// Validate 检查映射规则是否有效
+func (m KeyMapper) Validate() error {
+	switch m.Case {
+	case "", "upper", "lower", "keep":
+	default:
+		return fmt.Errorf("invalid --case %q, use upper, lower or keep", m.Case)
+	}
+	for key, name := range m.Overrides {
+		if !isEnvName(name) {
+			return fmt.Errorf("invalid variable name %q for %s", name, key)
+		}
+	}
+	if m.Prefix != "" && !isEnvName(m.Prefix) {
+		return fmt.Errorf("invalid --prefix %q", m.Prefix)
+	}
+	return nil
+}
+
+// isEnvName 判断是否为合法的环境变量名：字母、数字和下划线，且不以数字开头
+func isEnvName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case '0' <= r && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+// envVar 映射后的环境变量
+type envVar struct {
+	Name   string
+	Value  string
+	Source ConfigItem
+}
+
+// scopeItems 查询作用域内的配置项，raw 为 false 时展开值中的 ${...} 引用
+func scopeItems(project, env, module string, raw bool) ([]ConfigItem, error) {
+	conditions, params := scopeConditions(project, env, module)
+	items := queryConfigItems(conditions, params)
+	if raw {
+		return items, nil
+	}
+	return items, newResolver("").resolveItems(items)
+}
+
+// mapEnvVars 将配置项映射为环境变量，按变量名排序
+// 不同配置项映射到同一变量名且值不同时返回错误，需要用 -p/-e/-m 缩小范围或用 --map 重命名
+func mapEnvVars(configs []ConfigItem, mapper KeyMapper) ([]envVar, error) {
+	byName := map[string]envVar{}
+	var conflicts []string
+	for _, config := range configs {
+		name := mapper.Name(constant.SafeStr(config.ConfigKey))
+		value := constant.SafeStr(config.ConfigValue)
+		if existing, ok := byName[name]; ok {
+			if existing.Value != value {
+				conflicts = append(conflicts, fmt.Sprintf("%s (%s, %s)", name, describeItem(existing.Source), describeItem(config)))
+			}
+			continue
+		}
+		byName[name] = envVar{Name: name, Value: value, Source: config}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("several keys map to the same variable, narrow the scope with -p/-e/-m or use --map: %s", strings.Join(conflicts, "; "))
+	}
+
+	vars := make([]envVar, 0, len(byName))
+	for _, v := range byName {
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars, nil
+}
+
+// describeItem 以 project/env/module:key 的形式描述配置项
+func describeItem(c ConfigItem) string {
+	return fmt.Sprintf("%s/%s/%s:%s", constant.SafeStr(c.Project), constant.SafeStr(c.Env), constant.SafeStr(c.Module), constant.SafeStr(c.ConfigKey))
+}
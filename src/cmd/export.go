@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+)
+
+// HandleExportCommand 将作用域内的全部配置导出为文件，file 为 - 时输出到标准输出
+// 作用域过滤与 list 命令一致；dotenv 按 mapper 映射为环境变量名，
+// json/yaml 按点分隔的键输出嵌套对象，值按 config_type 输出为数字、布尔值或 JSON
+// raw 为 true 时不展开值中的 ${...} 引用
+func HandleExportCommand(project, env, module string, format, file string, mapper KeyMapper, raw bool) {
+	switch format {
+	case "dotenv", "json", "yaml":
+	default:
+		fmt.Fprintf(os.Stderr, "Unsupported export format: %s (supported: dotenv, json, yaml)\n", format)
+		os.Exit(1)
+	}
+
+	configs, err := scopeItems(project, env, module, raw)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v (use --raw to export stored values)\n", err)
+		os.Exit(1)
+	}
+
+	var content string
+	count := len(configs)
+	if format == "dotenv" {
+		vars, err := mapEnvVars(configs, mapper)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		content = formatDotenv(vars)
+		count = len(vars)
+	} else {
+		doc, err := typedDocument(configs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if format == "json" {
+			encoded, err := json.MarshalIndent(doc, "", "  ")
+			if err != nil {
+				log.Fatalf("Failed to encode JSON: %v", err)
+			}
+			content = string(encoded) + "\n"
+		} else if content, err = toYAML(doc); err != nil {
+			log.Fatalf("Failed to encode YAML: %v", err)
+		}
+	}
+
+	if file == "-" {
+		fmt.Print(content)
+		return
+	}
+	// 导出内容包含明文配置，仅允许当前用户读写
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		log.Fatalf("Failed to write %s: %v", file, err)
+	}
+	fmt.Printf("Exported %d keys to %s\n", count, file)
+}
+
+// typedDocument 将配置按点分隔的键组装为嵌套对象，值按 config_type 转换
+// 同一个键在多个作用域中取值不同，或某个键同时是另一个键的上级时返回错误
+func typedDocument(configs []ConfigItem) (map[string]interface{}, error) {
+	doc := map[string]interface{}{}
+	sources := map[string]ConfigItem{}
+	for _, config := range configs {
+		key := constant.SafeStr(config.ConfigKey)
+		value := typedValue(constant.SafeStr(config.ConfigType), constant.SafeStr(config.ConfigValue))
+		if existing, ok := sources[key]; ok {
+			if constant.SafeStr(existing.ConfigValue) != constant.SafeStr(config.ConfigValue) {
+				return nil, fmt.Errorf("%s has different values in %s and %s, narrow the scope with -p/-e/-m",
+					key, describeItem(existing), describeItem(config))
+			}
+			continue
+		}
+		sources[key] = config
+
+		node := doc
+		parts := strings.Split(key, ".")
+		for i, part := range parts[:len(parts)-1] {
+			child, ok := node[part]
+			if !ok {
+				child = map[string]interface{}{}
+				node[part] = child
+			}
+			next, ok := child.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s cannot be nested under %s, which has a value", key, strings.Join(parts[:i+1], "."))
+			}
+			node = next
+		}
+		last := parts[len(parts)-1]
+		if _, ok := node[last]; ok {
+			return nil, fmt.Errorf("%s has a value but other keys are nested under it", key)
+		}
+		node[last] = value
+	}
+	return doc, nil
+}
+
+// formatDotenv 生成 .env 文件内容
+func formatDotenv(vars []envVar) string {
+	var b strings.Builder
+	for _, v := range vars {
+		fmt.Fprintf(&b, "%s=%s\n", v.Name, quoteDotenv(v.Value))
+	}
+	return b.String()
+}
+
+// quoteDotenv 按 dotenv 规则引用值
+// 普通值不加引号；含空格、# 等字符时使用单引号（内容按字面解释）；
+// 含换行或单引号时使用双引号并转义 \ " $ ` 及控制字符，避免 source、docker compose 等展开变量
+func quoteDotenv(value string) string {
+	if value == "" {
+		return ""
+	}
+	if isPlainDotenv(value) {
+		return value
+	}
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(value) + `"`
+}
+
+func isPlainDotenv(value string) bool {
+	for _, r := range value {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		case strings.ContainsRune("_-./:@%+,=", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import "testing"
+
+func TestQuoteDotenv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"localhost", "localhost"},
+		{"jdbc:mysql://db:3306/app", "jdbc:mysql://db:3306/app"},
+		{"hello world", "'hello world'"},
+		{"a#b", "'a#b'"},
+		{"$HOME", "'$HOME'"},
+		{"it's", `"it's"`},
+		{"it's $HOME", `"it's \$HOME"`},
+		{"it's `date`", "\"it's \\`date\\`\""},
+		{`say "hi" it's`, `"say \"hi\" it's"`},
+		{"line1\nline2", `"line1\nline2"`},
+		{`back\slash it's`, `"back\\slash it's"`},
+	}
+	for _, tt := range tests {
+		if got := quoteDotenv(tt.value); got != tt.want {
+			t.Errorf("quoteDotenv(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -6,95 +6,170 @@ import (
 	"strings"
 
 	"github.com/zhangymPerson/dev-env-manage/src/constant"
-	"github.com/zhangymPerson/dev-env-manage/src/db"
 	"github.com/zhangymPerson/dev-env-manage/src/log"
 	"github.com/zhangymPerson/dev-env-manage/src/models"
 )
 
-func HandleGetCommand(project, env, module string, verbose bool, key string) {
+// HandleGetCommand handles the get command
+// at 不为空时返回该时间点的值（格式见 parseTimeArg）
+// 值中的 ${...} 引用在读取时展开（见 resolver），raw 为 true 时输出原始值
+func HandleGetCommand(project, env, module string, verbose bool, key, at string, raw bool) {
 	if key == "" {
 		fmt.Println("Usage: dem get <key>")
 	}
+	if at != "" {
+		var err error
+		if at, err = parseTimeArg(at); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
 
-	// 构建查询条件和参数
-	query, params := buildQueryConditions(project, env, module, key)
-
-	// 输出最终执行的SQL
 	if verbose {
-		log.Info("=== 执行的SQL查询 ===\n")
-		log.Info("第一级查询 (config_key): %s\n", query.configKeyQuery)
-		log.Info("参数: %v\n", append([]interface{}{}, params...))
-		log.Info("第二级查询 (config_alias): %s\n", query.configAliasQuery)
-		log.Info("参数: %v\n", append([]interface{}{}, params...))
-		log.Info("第三级查询 (auto_alias): %s\n", query.autoAliasQuery)
-		log.Info("参数: %v\n", append([]interface{}{}, params...))
-		log.Info("==================\n\n")
-	}
-
-	// 三级查询逻辑：config_key -> config_alias -> auto_alias
-	// 收集所有匹配的配置项
-	var configs []models.ConfigMaster
+		log.Info("Lookup order for %s: %s, then any matching scope", key, strings.Join(scopeLayerNames(project, env, module), " -> "))
+	}
 
-	// 第一级：查询 config_key = key
-	rows, err := db.DB.Query(query.configKeyQuery, params...)
+	var configs []models.ConfigMaster
+	var source keySource
+	var err error
+	if at != "" {
+		configs, source, err = lookupKeyAt(project, env, module, key, at)
+	} else {
+		configs, source, err = lookupKey(project, env, module, key)
+	}
 	if err != nil {
-		log.Fatalf("Failed to execute config_key query: %v", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var config models.ConfigMaster
-		err = rows.Scan(
-			&config.Project, &config.Env, &config.Module,
-			&config.ConfigKey, &config.ConfigValue, &config.ConfigAlias, &config.AutoAlias,
-		)
-		if err != nil {
-			log.Fatalf("Failed to scan config: %v", err)
+		log.Fatalf("Failed to query %s: %v", key, err)
+	}
+	if !raw {
+		if err := newResolver(at).resolveConfigs(configs); err != nil {
+			fmt.Fprintf(os.Stderr, "%v (use --raw to show the stored value)\n", err)
+			os.Exit(1)
 		}
-		configs = append(configs, config)
 	}
-
+	if structuredOutput() {
+		printGetResult(key, configs, source)
+		return
+	}
+	if verbose && len(configs) > 0 {
+		// 来源输出到标准错误，不影响标准输出中的值
+		fmt.Fprintf(os.Stderr, "Source: %s (%s)\n", describeConfig(configs[0]), source)
+	}
 	printInfo(configs, verbose)
+}
 
-	// 第二级：查询 config_alias = key
-	rows, err = db.DB.Query(query.configAliasQuery, params...)
-	if err != nil {
-		log.Fatalf("Failed to execute config_alias query: %v", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var config models.ConfigMaster
-		err = rows.Scan(
-			&config.Project, &config.Env, &config.Module,
-			&config.ConfigKey, &config.ConfigValue, &config.ConfigAlias, &config.AutoAlias,
-		)
-		if err != nil {
-			log.Fatalf("Failed to scan config: %v", err)
+// lookupColumns 三级查询依次匹配的列：config_key -> config_alias -> auto_alias
+var lookupColumns = []string{"config_key", "config_alias", "auto_alias"}
+
+// keySource 描述 lookupKey 在哪一层、通过哪一列找到配置
+type keySource struct {
+	Layer    [3]string `json:"-"`          // 匹配的 project/env/module 层
+	Index    int       `json:"layer"`      // 层的序号，从 1 开始；通配查询找到时为 0
+	Layers   int       `json:"layers"`     // 总层数
+	Column   string    `json:"matched_by"` // 匹配的列
+	Wildcard bool      `json:"wildcard"`   // 各层均未找到，由 default 通配查询找到
+}
+
+func (s keySource) String() string {
+	if s.Wildcard {
+		return fmt.Sprintf("matched %s outside the fallback layers", s.Column)
+	}
+	return fmt.Sprintf("layer %d of %d, matched %s", s.Index, s.Layers, s.Column)
+}
+
+// scopeLayers 返回查找配置时依次尝试的作用域层，越具体的层优先级越高：
+// (app, prod, api) -> (app, prod, default) -> (app, default, default) -> (default, default, default)
+func scopeLayers(project, env, module string) [][3]string {
+	candidates := [][3]string{
+		{project, env, module},
+		{project, env, "default"},
+		{project, "default", "default"},
+		{"default", "default", "default"},
+	}
+	var layers [][3]string
+	for _, layer := range candidates {
+		if len(layers) == 0 || layers[len(layers)-1] != layer {
+			layers = append(layers, layer)
 		}
-		configs = append(configs, config)
 	}
-	printInfo(configs, verbose)
-	// 第三级：查询 auto_alias = key
-	rows, err = db.DB.Query(query.autoAliasQuery, params...)
-	if err != nil {
-		log.Fatalf("Failed to execute auto_alias query: %v", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var config models.ConfigMaster
-		err = rows.Scan(
-			&config.Project, &config.Env, &config.Module,
-			&config.ConfigKey, &config.ConfigValue, &config.ConfigAlias, &config.AutoAlias,
-		)
-		if err != nil {
-			log.Fatalf("Failed to scan config: %v", err)
+	return layers
+}
+
+func scopeLayerNames(project, env, module string) []string {
+	var names []string
+	for _, layer := range scopeLayers(project, env, module) {
+		names = append(names, strings.Join(layer[:], "/"))
+	}
+	return names
+}
+
+// lookupKey 按作用域层查找配置，每层内按三级查询逻辑（config_key -> config_alias -> auto_alias）匹配，
+// 返回第一个存在匹配项的结果；各层均未找到时按原有方式查询（值为 default 的维度不过滤）
+func lookupKey(project, env, module, key string) ([]models.ConfigMaster, keySource, error) {
+	return lookupLayered(project, env, module, key, true, queryMaster)
+}
+
+// lookupKeyAt 与 lookupKey 相同，但由 config_master 与 config_history 还原 at 时刻的状态
+func lookupKeyAt(project, env, module, key, at string) ([]models.ConfigMaster, keySource, error) {
+	return lookupLayered(project, env, module, key, true, func(conditions []string, params []interface{}) ([]models.ConfigMaster, error) {
+		return queryConfigsAt(conditions, params, at)
+	})
+}
+
+// lookupLayered 依次在各作用域层中查找 key，wildcard 为 true 时最后使用 default 通配查询
+func lookupLayered(project, env, module, key string, wildcard bool,
+	query func(conditions []string, params []interface{}) ([]models.ConfigMaster, error)) ([]models.ConfigMaster, keySource, error) {
+	layers := scopeLayers(project, env, module)
+	for i, layer := range layers {
+		for _, column := range lookupColumns {
+			configs, err := query([]string{"project=?", "env=?", "module=?", column + "=?"},
+				[]interface{}{layer[0], layer[1], layer[2], key})
+			if err != nil || len(configs) > 0 {
+				return configs, keySource{Layer: layer, Index: i + 1, Layers: len(layers), Column: column}, err
+			}
 		}
-		configs = append(configs, config)
 	}
-	printInfo(configs, verbose)
+	if !wildcard {
+		return nil, keySource{}, nil
+	}
+	conditions, params := scopeConditions(project, env, module)
+	if len(conditions) == 3 {
+		// 三个维度均已指定时通配查询与第一层相同
+		return nil, keySource{}, nil
+	}
+	for _, column := range lookupColumns {
+		configs, err := query(append(conditions, column+"=?"), append(params, key))
+		if err != nil || len(configs) > 0 {
+			return configs, keySource{Layer: [3]string{project, env, module}, Column: column, Wildcard: true}, err
+		}
+	}
+	return nil, keySource{}, nil
+}
 
+// getResult get 命令的结构化输出：找到的配置及其来源
+type getResult struct {
+	models.ConfigMaster
+	keySource
+}
+
+// get 结构化输出时 table/csv 的列
+var getOutputColumns = []string{"project", "env", "module", "config_key", "config_value", "config_type", "layer", "matched_by"}
+
+// printGetResult 以结构化格式输出 get 的结果；未找到或匹配到多个配置时报错退出
+func printGetResult(key string, configs []models.ConfigMaster, source keySource) {
+	switch len(configs) {
+	case 0:
+		fmt.Fprintf(os.Stderr, "Key not found: %s\n", key)
+		os.Exit(1)
+	case 1:
+		result := getResult{ConfigMaster: configs[0], keySource: source}
+		printStructured(result, result, getOutputColumns)
+	default:
+		fmt.Fprintf(os.Stderr, "%s matches %d keys, narrow the scope with -p/-e/-m:\n", key, len(configs))
+		for _, config := range configs {
+			fmt.Fprintf(os.Stderr, "  %s\n", describeConfig(config))
+		}
+		os.Exit(1)
+	}
 }
 
 func printInfo(configs []models.ConfigMaster, verbose bool) {
@@ -122,15 +197,8 @@ func printInfo(configs []models.ConfigMaster, verbose bool) {
 	}
 }
 
-// QueryConditions 定义查询条件和参数
-type QueryConditions struct {
-	configKeyQuery   string
-	configAliasQuery string
-	autoAliasQuery   string
-}
-
-// buildQueryConditions 根据参数构建查询条件
-func buildQueryConditions(project, env, module, key string) (QueryConditions, []interface{}) {
+// scopeConditions 根据项目、环境、模块构建过滤条件，值为 default 时不过滤
+func scopeConditions(project, env, module string) ([]string, []interface{}) {
 	var conditions []string
 	var params []interface{}
 
@@ -151,30 +219,5 @@ func buildQueryConditions(project, env, module, key string) (QueryConditions, []
 		conditions = append(conditions, "module=?")
 		params = append(params, module)
 	}
-
-	// 构建基础查询模板
-	baseQuery := "SELECT project, env, module, config_key, config_value, config_alias, auto_alias FROM config_master"
-
-	var whereClause string
-	// 如果有条件，构建WHERE子句
-	if len(conditions) > 0 {
-		whereClause = " WHERE " + strings.Join(conditions, " AND ") + " AND "
-	} else {
-		whereClause = " WHERE " // 没有条件时直接使用WHERE
-	}
-
-	// 构建三级查询的完整SQL
-	configKeyQuery := baseQuery + whereClause + "config_key=?"
-	configAliasQuery := baseQuery + whereClause + "config_alias=?"
-	autoAliasQuery := baseQuery + whereClause + "auto_alias=?"
-
-	// 为每个查询添加key参数
-	configKeyParams := append([]interface{}{}, params...)
-	configKeyParams = append(configKeyParams, key)
-
-	return QueryConditions{
-		configKeyQuery:   configKeyQuery,
-		configAliasQuery: configAliasQuery,
-		autoAliasQuery:   autoAliasQuery,
-	}, configKeyParams
+	return conditions, params
 }
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+)
+
+const historyColumns = `id, project, env, module, config_key, config_alias, auto_alias, config_value,
+	config_type, description, is_encrypted, sort_order, created_time, updated_time, version, changed_by, change_type`
+
+// history 结构化输出时 table/csv 的列
+var historyOutputColumns = []string{"id", "version", "changed_by", "change_type", "project", "env", "module", "config_key", "config_type", "config_value"}
+
+// HandleHistoryCommand 显示配置项的全部历史版本（新到旧）
+// key 依次按 config_key、config_alias、auto_alias 匹配；since 为空时不限制时间，limit 为 0 时不限制条数
+func HandleHistoryCommand(project, env, module string, verbose bool, key, since string, limit int) {
+	if since != "" {
+		var err error
+		if since, err = parseTimeArg(since); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	history, err := queryHistory(project, env, module, key, since)
+	if err != nil {
+		log.Fatalf("Failed to query config history: %v", err)
+	}
+	if limit > 0 && len(history) > limit {
+		history = history[:limit]
+	}
+	if structuredOutput() {
+		if history == nil {
+			history = []models.ConfigHistory{}
+		}
+		printStructured(history, history, historyOutputColumns)
+		return
+	}
+	if len(history) == 0 {
+		fmt.Printf("No history found for key: %s\n", key)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tVERSION\tCHANGED BY\tCHANGE\tPROJECT\tENV\tMODULE\tKEY\tALIAS\tTYPE\tVALUE")
+	for _, h := range history {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			h.ID, formatTime(h.Version), constant.SafeStr(h.ChangedBy), constant.SafeStr(h.ChangeType),
+			constant.SafeStr(h.Project), constant.SafeStr(h.Env), constant.SafeStr(h.Module),
+			constant.SafeStr(h.ConfigKey), constant.SafeStr(h.ConfigAlias), constant.SafeStr(h.ConfigType),
+			singleLine(constant.SafeStr(h.ConfigValue)))
+		if verbose && h.Description != nil && *h.Description != "" {
+			fmt.Fprintf(w, "\t\t\t\t\t\t\t\t\t\t# %s\n", singleLine(*h.Description))
+		}
+	}
+	w.Flush()
+}
+
+// queryHistory 查询配置项的历史版本，按 version 倒序排列
+// 与 get 命令一致，依次尝试 config_key、config_alias、auto_alias，命中即停止
+func queryHistory(project, env, module, key, since string) ([]models.ConfigHistory, error) {
+	conditions, params := scopeConditions(project, env, module)
+	if since != "" {
+		conditions = append(conditions, "version >= ?")
+		params = append(params, since)
+	}
+
+	for _, column := range []string{"config_key", "config_alias", "auto_alias"} {
+		where := append(append([]string{}, conditions...), column+"=?")
+		query := "SELECT " + historyColumns + " FROM config_history WHERE " + strings.Join(where, " AND ") +
+			" ORDER BY version DESC, id DESC"
+		history, err := scanHistory(query, append(append([]interface{}{}, params...), key)...)
+		if err != nil {
+			return nil, err
+		}
+		if len(history) > 0 {
+			return history, nil
+		}
+	}
+	return nil, nil
+}
+
+// scanHistory 执行查询并解密结果中的配置值
+func scanHistory(query string, params ...interface{}) ([]models.ConfigHistory, error) {
+	rows, err := db.DB.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.ConfigHistory
+	for rows.Next() {
+		var h models.ConfigHistory
+		err := rows.Scan(
+			&h.ID, &h.Project, &h.Env, &h.Module, &h.ConfigKey, &h.ConfigAlias, &h.AutoAlias, &h.ConfigValue,
+			&h.ConfigType, &h.Description, &h.IsEncrypted, &h.SortOrder, &h.CreatedTime, &h.UpdatedTime,
+			&h.Version, &h.ChangedBy, &h.ChangeType,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.DecryptConfig(&h.ConfigMaster); err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+// singleLine 将多行文本转义为单行，便于表格输出
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`).Replace(s)
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/settings"
+)
+
+// HookStateEnv 记录 shell 钩子已加载的状态：<指纹>:<变量名,...>
+// 指纹由上下文文件、作用域和配置的最后修改时间计算，变化时重新加载
+const HookStateEnv = "DEM_HOOK_STATE"
+
+// 各 shell 的钩子脚本，%[1]s 为调用 dem 的命令，%[2]s 为 hook export 的额外参数
+var hookScripts = map[string]string{
+	ShellBash: `_dem_hook() {
+  local previous_exit_status=$?
+  eval "$(%[1]s hook export bash%[2]s)"
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_dem_hook;"* ]]; then
+  PROMPT_COMMAND="_dem_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`,
+	ShellZsh: `_dem_hook() {
+  eval "$(%[1]s hook export zsh%[2]s)"
+}
+typeset -ag precmd_functions
+if (( ! ${precmd_functions[(I)_dem_hook]} )); then
+  precmd_functions=(_dem_hook $precmd_functions)
+fi
+`,
+	ShellFish: `function __dem_hook --on-event fish_prompt
+    %[1]s hook export fish%[2]s | source
+end
+`,
+}
+
+// HandleHookCommand 输出指定 shell 的钩子脚本，在 shell 配置文件中 eval 后，
+// 每次显示提示符时根据当前目录的 .dem 上下文文件加载或卸载配置
+// invocation 为调用 dem 的命令及全局参数（如 -c 指定的设置文件）；raw 为 true 时钩子加载原始值，不展开 ${...} 引用
+func HandleHookCommand(shell string, invocation []string, raw bool) {
+	script, ok := hookScripts[shell]
+	if !ok {
+		log.Fatalf("Unsupported shell for hook: %s (supported: bash, zsh, fish)", shell)
+	}
+	quoted := make([]string, len(invocation))
+	for i, arg := range invocation {
+		quoted[i] = shellQuote(shell, arg)
+	}
+	var extra string
+	if raw {
+		extra = " --raw"
+	}
+	fmt.Printf(script, strings.Join(quoted, " "), extra)
+}
+
+// HandleHookExportCommand 由钩子在每次显示提示符时调用，输出切换环境变量所需的语句
+// 只有已通过 dem allow 允许的上下文文件才会被加载；离开目录时卸载之前加载的变量
+// 错误信息输出到标准错误，不影响 shell 的使用；raw 为 true 时不展开值中的 ${...} 引用
+func HandleHookExportCommand(shell, project, env, module, allowFile string, raw bool) {
+	previous, loaded := parseHookState(os.Getenv(HookStateEnv))
+
+	fingerprint, vars, err := hookTarget(project, env, module, allowFile, raw)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dem: %v\n", err)
+		return
+	}
+	if fingerprint == previous {
+		return
+	}
+
+	current := map[string]bool{}
+	for _, v := range vars {
+		current[v.Name] = true
+	}
+	var unloaded int
+	for _, name := range loaded {
+		if !current[name] {
+			fmt.Println(unsetStatement(shell, name))
+			unloaded++
+		}
+	}
+	names := make([]string, 0, len(vars))
+	for _, v := range vars {
+		fmt.Println(exportStatement(shell, v.Name, v.Value))
+		names = append(names, v.Name)
+	}
+
+	if fingerprint == "" {
+		fmt.Println(unsetStatement(shell, HookStateEnv))
+	} else {
+		fmt.Println(exportStatement(shell, HookStateEnv, fingerprint+":"+strings.Join(names, ",")))
+	}
+	if len(vars) > 0 {
+		fmt.Fprintf(os.Stderr, "dem: loaded %d variables from %s/%s/%s\n", len(vars), project, env, module)
+	} else if unloaded > 0 {
+		fmt.Fprintf(os.Stderr, "dem: unloaded %d variables\n", unloaded)
+	}
+}
+
+// hookTarget 返回当前目录应加载的变量及其指纹，没有上下文文件时指纹为空
+// 上下文文件未被允许时返回以 blocked- 开头的指纹，提示只输出一次
+func hookTarget(project, env, module, allowFile string, raw bool) (string, []envVar, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", nil, err
+	}
+	ctx, err := settings.FindContext(cwd)
+	if err != nil || ctx == nil {
+		return "", nil, err
+	}
+
+	allowList, err := settings.LoadAllowList(allowFile)
+	if err != nil {
+		return "", nil, err
+	}
+	allowed, err := allowList.Allowed(ctx.Path)
+	if err != nil {
+		return "", nil, err
+	}
+	if !allowed {
+		fmt.Fprintf(os.Stderr, "dem: %s is not allowed, run 'dem allow' to load it\n", ctx.Path)
+		return "blocked-" + fingerprintOf(ctx.Path), nil, nil
+	}
+
+	var count int
+	var lastUpdate string
+	if err := db.DB.QueryRow("SELECT COUNT(*), IFNULL(MAX(updated_time), '') FROM config_master").Scan(&count, &lastUpdate); err != nil {
+		return "", nil, err
+	}
+	configs, err := scopeItems(project, env, module, raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("%v (install the hook with 'dem hook <shell> --raw' to load stored values)", err)
+	}
+	vars, err := mapEnvVars(configs, KeyMapper{})
+	if err != nil {
+		return "", nil, err
+	}
+	return fingerprintOf(ctx.Path, project, env, module, fmt.Sprint(count), lastUpdate), vars, nil
+}
+
+// HandleAllowCommand 允许或撤销 dir 中（或其上级目录中）的 .dem 上下文文件被钩子自动加载
+func HandleAllowCommand(dir, allowFile string, allow bool) {
+	ctx, err := settings.FindContext(dir)
+	if err != nil {
+		log.Fatalf("Failed to read context file: %v", err)
+	}
+	if ctx == nil {
+		fmt.Printf("No %s file found in %s or its parents\n", constant.ContextFileName, dir)
+		os.Exit(1)
+	}
+	path, err := filepath.Abs(ctx.Path)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	allowList, err := settings.LoadAllowList(allowFile)
+	if err != nil {
+		log.Fatalf("Failed to read allow list: %v", err)
+	}
+	if allow {
+		if err := allowList.Allow(path); err != nil {
+			log.Fatalf("Failed to allow %s: %v", path, err)
+		}
+	} else if !allowList.Deny(path) {
+		fmt.Printf("%s was not allowed\n", path)
+		return
+	}
+	if err := allowList.Save(); err != nil {
+		log.Fatalf("Failed to write allow list: %v", err)
+	}
+
+	if allow {
+		fmt.Printf("Allowed %s (project=%s env=%s module=%s)\n", path, ctx.Project, ctx.Env, ctx.Module)
+	} else {
+		fmt.Printf("Denied %s\n", path)
+	}
+}
+
+func parseHookState(state string) (string, []string) {
+	fingerprint, names, _ := strings.Cut(state, ":")
+	if names == "" {
+		return fingerprint, nil
+	}
+	return fingerprint, strings.Split(names, ",")
+}
+
+func fingerprintOf(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+)
+
+// 导入时与已有配置冲突的处理方式
+const (
+	ConflictFail      = "fail"      // 存在不同的值时中止导入（默认）
+	ConflictOverwrite = "overwrite" // 覆盖已有值
+	ConflictSkip      = "skip"      // 保留已有值
+)
+
+// HandleImportCommand 将文件中的配置在一个事务中批量写入作用域 project/env/module
+// 嵌套的 JSON/YAML 对象展开为点分隔的键，config_type 根据值的类型设置
+func HandleImportCommand(project, env, module string, file, format, conflict string, dryRun bool, operator string) {
+	if format == "" || format == "auto" {
+		var err error
+		if format, err = detectFormat(file); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", file, err)
+	}
+	entries, err := parseEntries(format, content)
+	if err != nil {
+		log.Fatalf("Failed to parse %s: %v", file, err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No keys found in file.")
+		return
+	}
+
+	// 查询作用域内已有的配置，用于处理冲突
+	existing := map[string]models.ConfigMaster{}
+	current, err := queryMaster([]string{"project=?", "env=?", "module=?"}, []interface{}{project, env, module})
+	if err != nil {
+		log.Fatalf("Failed to query existing config: %v", err)
+	}
+	for _, c := range current {
+		existing[constant.SafeStr(c.ConfigKey)] = c
+	}
+
+	// dotenv/properties 中的值没有类型，已存在的键沿用原有类型；覆盖时值必须符合该类型
+	if format == "dotenv" || format == "properties" {
+		mismatched := 0
+		for i, entry := range entries {
+			old, ok := existing[entry.Key]
+			if !ok || old.ConfigType == nil {
+				continue
+			}
+			value, err := validateValue(*old.ConfigType, entry.Value)
+			if err != nil {
+				if conflict == ConflictOverwrite {
+					mismatched++
+					fmt.Printf("!    %s: %v\n", entry.Key, err)
+				}
+				continue
+			}
+			entries[i].Value, entries[i].Type = value, *old.ConfigType
+		}
+		if mismatched > 0 {
+			fmt.Printf("%d keys do not match the type of the existing value\n", mismatched)
+			os.Exit(1)
+		}
+	}
+
+	invalid, err := checkEntries(project, env, module, entries)
+	if err != nil {
+		log.Fatalf("Failed to query schema: %v", err)
+	}
+	if invalid > 0 {
+		fmt.Printf("%d keys do not match the schema of %s/%s\n", invalid, project, module)
+		os.Exit(1)
+	}
+
+	currentTime := time.Now()
+	var configs []models.ConfigMaster
+	var added, updated, unchanged, skipped, conflicts int
+	for _, entry := range entries {
+		action := "+"
+		old, ok := existing[entry.Key]
+		if ok {
+			switch {
+			case constant.SafeStr(old.ConfigValue) == entry.Value && constant.SafeStr(old.ConfigType) == entry.Type:
+				unchanged++
+				continue
+			case conflict == ConflictSkip:
+				skipped++
+				fmt.Printf("skip %s (exists)\n", entry.Key)
+				continue
+			case conflict == ConflictOverwrite:
+				action = "~"
+				updated++
+			default:
+				conflicts++
+				fmt.Printf("!    %s (exists with a different value)\n", entry.Key)
+				continue
+			}
+		} else {
+			added++
+		}
+		fmt.Printf("%s    %s (%s)\n", action, entry.Key, entry.Type)
+
+		if ok {
+			// 覆盖时保留别名、说明等文件中没有描述的字段
+			old.ConfigValue = constant.ToStrPtr(entry.Value)
+			old.ConfigType = constant.ToStrPtr(entry.Type)
+			old.UpdatedTime = constant.ToTimePtr(currentTime)
+			old.UpdatedBy = constant.ToStrPtr(operator)
+			configs = append(configs, old)
+			continue
+		}
+		alias := generateDefaultAlias(entry.Key)
+		configs = append(configs, models.ConfigMaster{
+			Project:     constant.ToStrPtr(project),
+			Env:         constant.ToStrPtr(env),
+			Module:      constant.ToStrPtr(module),
+			ConfigKey:   constant.ToStrPtr(entry.Key),
+			ConfigValue: constant.ToStrPtr(entry.Value),
+			ConfigAlias: constant.ToStrPtr(alias),
+			AutoAlias:   constant.ToStrPtr(alias),
+			ConfigType:  constant.ToStrPtr(entry.Type),
+			IsEncrypted: constant.ToIntPtr(1),
+			CreatedTime: constant.ToTimePtr(currentTime),
+			UpdatedTime: constant.ToTimePtr(currentTime),
+			UpdatedBy:   constant.ToStrPtr(operator),
+		})
+	}
+
+	fmt.Printf("%d to add, %d to update, %d unchanged, %d skipped\n", added, updated, unchanged, skipped)
+	if conflicts > 0 {
+		fmt.Printf("%d keys already exist with different values, use --overwrite or --skip-existing\n", conflicts)
+		os.Exit(1)
+	}
+	if dryRun {
+		fmt.Println("Dry run, nothing imported.")
+		return
+	}
+	if len(configs) == 0 {
+		return
+	}
+	if err := db.AddConfigs(configs); err != nil {
+		log.Fatalf("Failed to import config: %v", err)
+	}
+	fmt.Printf("Imported %d keys into %s/%s/%s\n", len(configs), project, env, module)
+}
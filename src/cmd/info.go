@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+)
+
+// HandleInfoCommand 显示 get 会返回的配置的全部字段、历史版本数以及作用域层的覆盖关系
+func HandleInfoCommand(project, env, module, key string) {
+	configs, source, err := lookupKey(project, env, module, key)
+	if err != nil {
+		log.Fatalf("Failed to query %s: %v", key, err)
+	}
+	if len(configs) == 0 {
+		fmt.Fprintf(os.Stderr, "Key not found: %s\n", key)
+		os.Exit(1)
+	}
+
+	if structuredOutput() && len(configs) > 1 {
+		printGetResult(key, configs, source)
+	}
+
+	layers := scopeLayers(project, env, module)
+	for i, config := range configs {
+		history, err := countHistory(config)
+		if err != nil {
+			log.Fatalf("Failed to count history of %s: %v", key, err)
+		}
+		shadows, err := shadowedLayers(config, source, layers)
+		if err != nil {
+			log.Fatalf("Failed to query fallback layers of %s: %v", key, err)
+		}
+		resolved, err := newResolver("").resolve(config)
+		if err != nil {
+			resolved = "error: " + err.Error()
+		}
+		result := infoResult{ConfigMaster: config, ResolvedValue: resolved, HistoryVersions: history, keySource: source, Shadows: shadows}
+		if result.Shadows == nil {
+			result.Shadows = []string{}
+		}
+
+		if structuredOutput() {
+			printStructured(result, result, infoOutputColumns)
+			return
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		printInfoResult(result)
+	}
+}
+
+// infoResult info 命令的结构化输出
+type infoResult struct {
+	models.ConfigMaster
+	ResolvedValue   string `json:"resolved_value"`   // 展开 ${...} 引用后的值
+	HistoryVersions int    `json:"history_versions"` // config_history 中的版本数
+	keySource
+	Shadows []string `json:"shadows"` // 被该配置覆盖的低优先级作用域层
+}
+
+// info 结构化输出时 table/csv 的列
+var infoOutputColumns = []string{"id", "project", "env", "module", "config_key", "config_value", "resolved_value", "config_type",
+	"is_encrypted", "created_time", "updated_time", "history_versions", "layer", "shadows"}
+
+func printInfoResult(r infoResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	row := func(name, value string) { fmt.Fprintf(w, "%s:\t%s\n", name, value) }
+	row("ID", fmt.Sprint(r.ID))
+	row("Project", constant.SafeStr(r.Project))
+	row("Env", constant.SafeStr(r.Env))
+	row("Module", constant.SafeStr(r.Module))
+	row("Key", constant.SafeStr(r.ConfigKey))
+	row("Value", singleLine(constant.SafeStr(r.ConfigValue)))
+	if r.ResolvedValue != constant.SafeStr(r.ConfigValue) {
+		row("Resolved", singleLine(r.ResolvedValue))
+	}
+	row("Alias", constant.SafeStr(r.ConfigAlias))
+	row("Auto alias", constant.SafeStr(r.AutoAlias))
+	row("Type", constant.SafeStr(r.ConfigType))
+	row("Description", constant.SafeStr(r.Description))
+	row("Encrypted", yesNo(r.IsEncrypted != nil && *r.IsEncrypted == 1))
+	row("Sort order", fmt.Sprint(constant.SafeInt(r.SortOrder)))
+	row("Created", formatTime(r.CreatedTime))
+	row("Updated", formatTime(r.UpdatedTime))
+	row("Updated by", constant.SafeStr(r.UpdatedBy))
+	row("History", fmt.Sprintf("%d versions", r.HistoryVersions))
+	row("Resolved from", r.keySource.String())
+	if len(r.Shadows) > 0 {
+		row("Shadows", strings.Join(r.Shadows, ", "))
+	} else {
+		row("Shadows", "none")
+	}
+	w.Flush()
+}
+
+// countHistory 返回配置在 config_history 中的版本数
+func countHistory(config models.ConfigMaster) (int, error) {
+	var count int
+	err := db.DB.QueryRow(
+		"SELECT COUNT(*) FROM config_history WHERE project=? AND env=? AND module=? AND config_key=?",
+		constant.SafeStr(config.Project), constant.SafeStr(config.Env), constant.SafeStr(config.Module), constant.SafeStr(config.ConfigKey),
+	).Scan(&count)
+	return count, err
+}
+
+// shadowedLayers 返回优先级低于匹配层、同样定义了该键而被覆盖的作用域层
+func shadowedLayers(config models.ConfigMaster, source keySource, layers [][3]string) ([]string, error) {
+	if source.Wildcard {
+		return nil, nil
+	}
+	var shadows []string
+	for _, layer := range layers[source.Index:] {
+		var count int
+		err := db.DB.QueryRow(
+			"SELECT COUNT(*) FROM config_master WHERE project=? AND env=? AND module=? AND config_key=?",
+			layer[0], layer[1], layer[2], constant.SafeStr(config.ConfigKey),
+		).Scan(&count)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			shadows = append(shadows, strings.Join(layer[:], "/"))
+		}
+	}
+	return shadows, nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
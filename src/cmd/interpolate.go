@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+)
+
+// 配置值中可以引用其他配置，读取时展开：
+//
+//	${db.host}               同一 project/env/module 中的配置
+//	${prod:db.host}          同一 project、module 中 prod 环境的配置
+//	${prod:database:db.host} 同一 project 中 prod 环境 database 模块的配置
+//	$${literal}              输出 ${literal}，不展开
+//
+// 引用的键与 get 一样按作用域层回退查找，并依次匹配 config_key、config_alias、auto_alias
+
+// resolver 展开配置值中的引用，缓存已展开的配置并检测循环引用
+type resolver struct {
+	at    string            // 不为空时按该时间点的状态展开（格式同 queryConfigsAt）
+	cache map[string]string // configIdentity -> 展开后的值
+	stack []string          // 正在展开的配置（project/env/module:key），用于检测循环引用
+}
+
+func newResolver(at string) *resolver {
+	return &resolver{at: at, cache: map[string]string{}}
+}
+
+// resolveConfigs 展开配置列表中的引用
+func (r *resolver) resolveConfigs(configs []models.ConfigMaster) error {
+	for i := range configs {
+		value, err := r.resolve(configs[i])
+		if err != nil {
+			return err
+		}
+		configs[i].ConfigValue = constant.ToStrPtr(value)
+	}
+	return nil
+}
+
+// resolveItems 展开配置项列表中的引用
+func (r *resolver) resolveItems(items []ConfigItem) error {
+	for i, item := range items {
+		value, err := r.resolve(models.ConfigMaster{
+			Project: item.Project, Env: item.Env, Module: item.Module,
+			ConfigKey: item.ConfigKey, ConfigValue: item.ConfigValue,
+		})
+		if err != nil {
+			return err
+		}
+		items[i].ConfigValue = constant.ToStrPtr(value)
+	}
+	return nil
+}
+
+// resolve 返回配置展开引用后的值，引用相对于该配置所在的作用域
+func (r *resolver) resolve(config models.ConfigMaster) (string, error) {
+	value := constant.SafeStr(config.ConfigValue)
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+	id := configIdentity(config)
+	if resolved, ok := r.cache[id]; ok {
+		return resolved, nil
+	}
+	name := describeConfig(config)
+	for i, entry := range r.stack {
+		if entry == name {
+			chain := append(append([]string{}, r.stack[i:]...), name)
+			return "", fmt.Errorf("reference cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	r.stack = append(r.stack, name)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+
+	var b strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			b.WriteString(value)
+			break
+		}
+		// $${ 为转义，原样输出 ${
+		if start > 0 && value[start-1] == '$' {
+			b.WriteString(value[:start-1] + "${")
+			value = value[start+2:]
+			continue
+		}
+		end := strings.Index(value[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("%s: unterminated reference in %q", describeConfig(config), value[start:])
+		}
+		ref := value[start+2 : start+end]
+		b.WriteString(value[:start])
+		value = value[start+end+1:]
+
+		target, err := r.lookupRef(config, ref)
+		if err != nil {
+			return "", err
+		}
+		resolved, err := r.resolve(target)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(resolved)
+	}
+
+	r.cache[id] = b.String()
+	return r.cache[id], nil
+}
+
+// lookupRef 查找引用 ref 指向的配置
+func (r *resolver) lookupRef(from models.ConfigMaster, ref string) (models.ConfigMaster, error) {
+	project, env, module := constant.SafeStr(from.Project), constant.SafeStr(from.Env), constant.SafeStr(from.Module)
+	parts := strings.Split(ref, ":")
+	key := parts[len(parts)-1]
+	switch len(parts) {
+	case 3:
+		env, module = parts[0], parts[1]
+	case 2:
+		env = parts[0]
+	case 1:
+	default:
+		return models.ConfigMaster{}, fmt.Errorf("%s: invalid reference ${%s}, use ${key}, ${env:key} or ${env:module:key}", describeConfig(from), ref)
+	}
+	if key == "" {
+		return models.ConfigMaster{}, fmt.Errorf("%s: empty reference ${%s}", describeConfig(from), ref)
+	}
+
+	query := queryMaster
+	if r.at != "" {
+		query = func(conditions []string, params []interface{}) ([]models.ConfigMaster, error) {
+			return queryConfigsAt(conditions, params, r.at)
+		}
+	}
+	configs, _, err := lookupLayered(project, env, module, key, false, query)
+	if err != nil {
+		return models.ConfigMaster{}, err
+	}
+	if len(configs) > 0 {
+		return configs[0], nil
+	}
+	return models.ConfigMaster{}, fmt.Errorf("%s: ${%s} refers to %s/%s/%s:%s, which does not exist", describeConfig(from), ref, project, env, module, key)
+}
+
+// describeConfig 以 project/env/module:key 的形式描述配置
+func describeConfig(c models.ConfigMaster) string {
+	return fmt.Sprintf("%s/%s/%s:%s", constant.SafeStr(c.Project), constant.SafeStr(c.Env), constant.SafeStr(c.Module), constant.SafeStr(c.ConfigKey))
+}
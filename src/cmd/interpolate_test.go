@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+)
+
+func TestResolve(t *testing.T) {
+	openTestDB(t,
+		`INSERT INTO config_master (project, env, module, config_key, config_value, is_encrypted) VALUES
+			('p', 'dev', 'm', 'db.host', 'localhost', 0),
+			('p', 'dev', 'm', 'db.port', '5432', 0),
+			('p', 'dev', 'm', 'db.url', 'postgres://${db.host}:${db.port}', 0),
+			('p', 'prod', 'm', 'db.host', 'db.prod', 0),
+			('p', 'prod', 'database', 'db.host', 'db.internal', 0),
+			('p', 'dev', 'm', 'loop.a', '${loop.b}', 0),
+			('p', 'dev', 'm', 'loop.b', '${loop.a}', 0),
+			('p', 'dev', 'm', 'self', 'x${self}', 0),
+			('p', 'dev', 'm', 'broken', 'via ${missing}', 0)`,
+	)
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"plain", "plain", ""},
+		{"${db.host}", "localhost", ""},
+		{"${db.url}/app", "postgres://localhost:5432/app", ""},
+		{"${prod:db.host}", "db.prod", ""},
+		{"${prod:database:db.host}", "db.internal", ""},
+		{"$${db.host}", "${db.host}", ""},
+		{"cost $5", "cost $5", ""},
+		{"${missing}", "", "p/dev/m:missing, which does not exist"},
+		{"${staging:db.host}", "", "p/staging/m:db.host, which does not exist"},
+		{"${broken}", "", "${missing} refers to p/dev/m:missing"},
+		{"${loop.a}", "", "reference cycle: p/dev/m:loop.a -> p/dev/m:loop.b -> p/dev/m:loop.a"},
+		{"${self}", "", "reference cycle: p/dev/m:self -> p/dev/m:self"},
+		{"${db.host", "", "unterminated reference"},
+		{"${}", "", "empty reference"},
+		{"${a:b:c:d}", "", "invalid reference"},
+	}
+	for _, tt := range tests {
+		got, err := newResolver("").resolve(models.ConfigMaster{
+			Project: constant.ToStrPtr("p"), Env: constant.ToStrPtr("dev"), Module: constant.ToStrPtr("m"),
+			ConfigKey: constant.ToStrPtr("test"), ConfigValue: constant.ToStrPtr(tt.value),
+		})
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("resolve(%q) = %q, %v, want error containing %q", tt.value, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolve(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/secret"
+)
+
+// HandleKeyRotateCommand 生成新的主密钥并重新加密所有配置及历史记录
+// resume 为 true 时不生成新密钥，只将剩余行迁移到当前密钥（用于继续中断的轮换）
+func HandleKeyRotateCommand(resume bool) {
+	if !resume {
+		keyID, err := secret.Rotate()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to generate new master key: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("New master key: %s\n", keyID)
+	}
+
+	counts, err := db.ReencryptAll()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to re-encrypt values, run 'dem key rotate --resume' to retry: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Re-encrypted %d config rows and %d history rows\n", counts["config_master"], counts["config_history"])
+
+	usage, err := db.KeyUsage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to count key usage: %v\n", err)
+		os.Exit(1)
+	}
+	removed, err := secret.Prune(usage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to retire old keys: %v\n", err)
+		os.Exit(1)
+	}
+	if len(removed) > 0 {
+		fmt.Printf("Retired keys: %s\n", strings.Join(removed, ", "))
+	}
+}
+
+// keyStatus key status 的结构化输出
+type keyStatus struct {
+	KeyFile string         `json:"key_file"`
+	Keys    []keyInfo      `json:"keys"`
+	Pending map[string]int `json:"pending"` // 不在当前密钥下的行数，plaintext 表示尚未加密
+}
+
+type keyInfo struct {
+	ID         string    `json:"id"`
+	Created    time.Time `json:"created"`
+	Passphrase string    `json:"passphrase"` // 口令来源：key file 或环境变量名
+	Rows       int       `json:"rows"`
+	Status     string    `json:"status"` // active 或 retired
+}
+
+// HandleKeyStatusCommand 显示密钥文件中的密钥及各自引用的行数
+func HandleKeyStatusCommand() {
+	result := keyStatus{KeyFile: secret.GetKeyFilePath(), Keys: []keyInfo{}, Pending: map[string]int{}}
+	// 尚未加密过任何值时不创建密钥
+	if exists, err := secret.HasKey(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read master key file: %v\n", err)
+		os.Exit(1)
+	} else if !exists {
+		if structuredOutput() {
+			printStructured(result, result.Keys, []string{"id", "created", "passphrase", "rows", "status"})
+			return
+		}
+		fmt.Printf("Key file: %s\n", result.KeyFile)
+		fmt.Println("No master key yet, one is created when the first value is encrypted.")
+		return
+	}
+
+	ring, err := secret.Keys()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read master key file: %v\n", err)
+		os.Exit(1)
+	}
+	usage, err := db.KeyUsage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to count key usage: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, mk := range ring.Keys {
+		info := keyInfo{ID: mk.ID, Created: mk.Created, Passphrase: "key file", Rows: usage[mk.ID], Status: "retired"}
+		if mk.Passphrase == "" {
+			info.Passphrase = secret.PassphraseEnv
+		}
+		if mk.ID == ring.Active {
+			info.Status = "active"
+		}
+		result.Keys = append(result.Keys, info)
+	}
+	// 引用未知密钥或尚未加密的行，需要执行 dem key rotate --resume
+	for keyID, n := range usage {
+		if keyID != ring.Active {
+			result.Pending[displayKeyID(keyID)] = n
+		}
+	}
+	if structuredOutput() {
+		printStructured(result, result.Keys, []string{"id", "created", "passphrase", "rows", "status"})
+		return
+	}
+
+	fmt.Printf("Key file: %s\n", result.KeyFile)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tCREATED\tPASSPHRASE\tROWS\tSTATUS")
+	for _, k := range result.Keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", k.ID, k.Created.Format("2006-01-02 15:04:05"), k.Passphrase, k.Rows, k.Status)
+	}
+	w.Flush()
+
+	var pending []string
+	for keyID, n := range result.Pending {
+		pending = append(pending, fmt.Sprintf("%s=%d", keyID, n))
+	}
+	if len(pending) > 0 {
+		sort.Strings(pending)
+		fmt.Printf("Rows not under the active key: %s (run 'dem key rotate --resume')\n", strings.Join(pending, ", "))
+	}
+}
+
+func displayKeyID(keyID string) string {
+	if keyID == "" {
+		return "plaintext"
+	}
+	return keyID
+}
@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/zhangymPerson/dev-env-manage/src/db"
 	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
 )
 
 type ConfigItem struct {
@@ -15,32 +18,88 @@ type ConfigItem struct {
 	ConfigValue *string
 	ConfigAlias *string
 	AutoAlias   *string
+	ConfigType  *string
+	IsEncrypted *int
 }
 
-func HandleListCommand(project, env, module string, verbose bool, show bool) {
-	// 根据参数动态构建查询条件
-	query := "SELECT project, env, module, config_key, config_value, config_alias, auto_alias FROM config_master WHERE 1=1"
+// list 结构化输出时 table/csv 的列
+var listOutputColumns = []string{"id", "project", "env", "module", "config_key", "config_value", "config_type", "config_alias", "auto_alias", "updated_time"}
+
+// HandleListCommand handles the list command
+// at 不为空时列出该时间点的配置（格式见 parseTimeArg）
+func HandleListCommand(project, env, module string, verbose bool, show bool, at string) {
+	conditions, params := scopeConditions(project, env, module)
+	if at != "" {
+		var err error
+		if at, err = parseTimeArg(at); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
 
-	// 如果指定了项目名，则添加项目条件
-	if project != "default" {
-		query += fmt.Sprintf(" AND project = '%s'", project)
+	if structuredOutput() {
+		// 结构化输出包含 config_master 的全部字段
+		var snapshot []models.ConfigMaster
+		var err error
+		if at != "" {
+			snapshot, err = queryConfigsAt(conditions, params, at)
+		} else {
+			snapshot, err = queryMaster(conditions, params)
+		}
+		if err != nil {
+			log.Fatalf("Failed to query config items: %v", err)
+		}
+		if snapshot == nil {
+			snapshot = []models.ConfigMaster{}
+		}
+		printStructured(snapshot, snapshot, listOutputColumns)
+		return
 	}
 
-	// 如果指定了环境名，则添加环境条件
-	if env != "default" {
-		query += fmt.Sprintf(" AND env = '%s'", env)
+	var configs []ConfigItem
+	if at != "" {
+		// 由 config_master 与 config_history 还原当时的状态
+		snapshot, err := queryConfigsAt(conditions, params, at)
+		if err != nil {
+			log.Fatalf("Failed to query config items: %v", err)
+		}
+		for _, c := range snapshot {
+			configs = append(configs, ConfigItem{
+				Project: c.Project, Env: c.Env, Module: c.Module, ConfigKey: c.ConfigKey,
+				ConfigValue: c.ConfigValue, ConfigAlias: c.ConfigAlias, AutoAlias: c.AutoAlias,
+				ConfigType: c.ConfigType, IsEncrypted: c.IsEncrypted,
+			})
+		}
+	} else {
+		configs = queryConfigItems(conditions, params)
 	}
 
-	// 如果指定了模块名，则添加模块条件
-	if module != "default" {
-		query += fmt.Sprintf(" AND module = '%s'", module)
+	if len(configs) == 0 {
+		fmt.Println("No configuration items found.")
+		return
+	}
+
+	// 根据verbose参数决定输出格式
+	if verbose {
+		printVerboseList(configs)
+	} else {
+		printSimpleList(configs, show)
+	}
+}
+
+// queryConfigItems 查询当前的配置项，按 project, env, module, config_key 排序
+func queryConfigItems(conditions []string, params []interface{}) []ConfigItem {
+	// 根据参数动态构建查询条件
+	query := "SELECT project, env, module, config_key, config_value, config_alias, auto_alias, config_type, is_encrypted FROM config_master"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
 	// 添加排序条件
 	query += " ORDER BY project, env, module, config_key"
 
 	// 查询配置项
-	rows, err := db.DB.Query(query)
+	rows, err := db.DB.Query(query, params...)
 	if err != nil {
 		log.Fatalf("Failed to query config items: %v", err)
 	}
@@ -53,29 +112,21 @@ func HandleListCommand(project, env, module string, verbose bool, show bool) {
 			&config.Project, &config.Env,
 			&config.Module, &config.ConfigKey,
 			&config.ConfigValue, &config.ConfigAlias,
-			&config.AutoAlias,
+			&config.AutoAlias, &config.ConfigType, &config.IsEncrypted,
 		)
 		if err != nil {
 			log.Fatalf("Failed to scan config item: %v", err)
 		}
+		if config.ConfigValue, err = db.DecryptValue(config.ConfigValue, config.IsEncrypted); err != nil {
+			log.Fatalf("Failed to decrypt config item: %v", err)
+		}
 		configs = append(configs, config)
 	}
 
 	if err = rows.Err(); err != nil {
 		log.Fatalf("Error iterating config items: %v", err)
 	}
-
-	if len(configs) == 0 {
-		fmt.Println("No configuration items found.")
-		return
-	}
-
-	// 根据verbose参数决定输出格式
-	if verbose {
-		printVerboseList(configs)
-	} else {
-		printSimpleList(configs, show)
-	}
+	return configs
 }
 
 func printSimpleList(configs []ConfigItem, show bool) {
@@ -206,6 +257,11 @@ func HandleListProjects() {
 		log.Fatalf("Error iterating projects: %v", err)
 	}
 
+	if structuredOutput() {
+		printNames(projects, "project")
+		return
+	}
+
 	// fmt.Println("Projects:")
 	for _, project := range projects {
 		if project != nil {
@@ -246,6 +302,11 @@ func HandleListEnvs(project string) {
 		log.Fatalf("Error iterating environments: %v", err)
 	}
 
+	if structuredOutput() {
+		printNames(envs, "env")
+		return
+	}
+
 	// 输出结果
 	if len(envs) == 0 {
 		fmt.Println("No environments found.")
@@ -304,6 +365,11 @@ func HandleListModules(project, env string) {
 		log.Fatalf("Error iterating modules: %v", err)
 	}
 
+	if structuredOutput() {
+		printNames(modules, "module")
+		return
+	}
+
 	// 输出结果
 	if len(modules) == 0 {
 		fmt.Println("No modules found.")
@@ -327,3 +393,16 @@ func HandleListModules(project, env string) {
 		}
 	}
 }
+
+// printNames 以结构化格式输出 list -p/-e/-m 的名称列表：json/yaml 为字符串数组，table/csv 为单列
+func printNames(names []*string, column string) {
+	values := []string{}
+	var rows []map[string]string
+	for _, name := range names {
+		if name != nil {
+			values = append(values, *name)
+			rows = append(rows, map[string]string{column: *name})
+		}
+	}
+	printStructured(values, rows, []string{column})
+}
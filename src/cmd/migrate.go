@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+)
+
+// migrationStatus db migrate --status 的结构化输出
+type migrationStatus struct {
+	Version     int        `json:"version"`
+	Name        string     `json:"name"`
+	Status      string     `json:"status"` // applied 或 pending
+	AppliedTime *time.Time `json:"applied_time,omitempty"`
+}
+
+// HandleMigrateCommand 应用尚未执行的迁移；status 为 true 时只显示各迁移的状态
+// 结构化输出时输出应用后各迁移的状态
+func HandleMigrateCommand(migrations []db.Migration, status bool) {
+	if !status {
+		applied, err := db.Migrate(migrations)
+		if err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		for _, m := range applied {
+			if !structuredOutput() {
+				fmt.Printf("Applied: %s\n", m.Name)
+			}
+		}
+	}
+
+	applied, err := db.AppliedMigrations()
+	if err != nil {
+		log.Fatalf("Failed to read schema version: %v", err)
+	}
+
+	if structuredOutput() {
+		result := []migrationStatus{}
+		for _, m := range migrations {
+			s := migrationStatus{Version: m.Version, Name: m.Name, Status: "pending"}
+			if a, ok := applied[m.Version]; ok {
+				s.Status = "applied"
+				s.AppliedTime = &a.AppliedTime
+			}
+			result = append(result, s)
+		}
+		printStructured(result, result, []string{"version", "name", "status", "applied_time"})
+		return
+	}
+
+	if status {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tMIGRATION\tSTATUS\tAPPLIED AT")
+		for _, m := range migrations {
+			if a, ok := applied[m.Version]; ok {
+				fmt.Fprintf(w, "%d\t%s\tapplied\t%s\n", m.Version, m.Name, a.AppliedTime.Local().Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Fprintf(w, "%d\t%s\tpending\t-\n", m.Version, m.Name)
+			}
+		}
+		w.Flush()
+	}
+
+	current := 0
+	for version := range applied {
+		current = max(current, version)
+	}
+	fmt.Printf("Schema version: %d\n", current)
+}
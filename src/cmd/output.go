@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 输出格式，由全局参数 --output 或设置文件中的 [output] format 指定
+//
+//	text   各命令原有的文本输出（默认）
+//	json   JSON，字段名与 models.ConfigMaster 等结构的 json 标签一致
+//	yaml   与 json 字段相同的 YAML
+//	table  带表头的对齐表格
+//	csv    带表头的 CSV，表头为 json 字段名
+//
+// 各命令的结构见 printHelp 中的 "Output schemas"
+const (
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputTable = "table"
+	OutputCSV   = "csv"
+)
+
+var outputFormat = OutputText
+
+// SetOutputFormat 设置命令的输出格式
+func SetOutputFormat(format string) error {
+	switch format {
+	case OutputText, OutputJSON, OutputYAML, OutputTable, OutputCSV:
+		outputFormat = format
+		return nil
+	}
+	return fmt.Errorf("invalid output format %q, use text, json, yaml, table or csv", format)
+}
+
+// structuredOutput 是否使用 text 以外的输出格式
+func structuredOutput() bool {
+	return outputFormat != OutputText
+}
+
+// printStructured 按当前输出格式输出结果
+// json/yaml 输出 doc 本身；table/csv 将 rows（切片或单个对象）的每个元素作为一行，
+// 按 columns 取出其 JSON 表示中的同名字段
+func printStructured(doc, rows interface{}, columns []string) {
+	if err := writeStructured(doc, rows, columns); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to format output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func writeStructured(doc, rows interface{}, columns []string) error {
+	switch outputFormat {
+	case OutputJSON:
+		encoded, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(encoded))
+	case OutputYAML:
+		encoded, err := toYAML(doc)
+		if err != nil {
+			return err
+		}
+		fmt.Print(encoded)
+	case OutputTable:
+		records, err := toRecords(rows, columns)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		header := make([]string, len(columns))
+		for i, column := range columns {
+			header[i] = strings.ToUpper(column)
+		}
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+		for _, record := range records {
+			for i := range record {
+				record[i] = singleLine(record[i])
+			}
+			fmt.Fprintln(w, strings.Join(record, "\t"))
+		}
+		return w.Flush()
+	case OutputCSV:
+		records, err := toRecords(rows, columns)
+		if err != nil {
+			return err
+		}
+		w := csv.NewWriter(os.Stdout)
+		w.Write(columns)
+		w.WriteAll(records)
+		return w.Error()
+	default:
+		return fmt.Errorf("output format %s is not structured", outputFormat)
+	}
+	return nil
+}
+
+// toYAML 将 v 的 JSON 表示转换为 YAML，保留 json 标签定义的字段名与顺序
+func toYAML(v interface{}) (string, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(encoded, &node); err != nil {
+		return "", err
+	}
+	resetStyle(&node)
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return "", err
+	}
+	return out.String(), encoder.Close()
+}
+
+// resetStyle 清除从 JSON 解析得到的流式、引号样式，输出块状 YAML
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// toRecords 将 rows 的 JSON 表示转换为按 columns 排列的字符串行
+func toRecords(rows interface{}, columns []string) ([][]string, error) {
+	encoded, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	var objects []interface{}
+	switch v := decoded.(type) {
+	case []interface{}:
+		objects = v
+	case nil:
+	default:
+		objects = []interface{}{v}
+	}
+
+	records := make([][]string, 0, len(objects))
+	for _, obj := range objects {
+		fields, _ := obj.(map[string]interface{})
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = cellValue(fields[column])
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func cellValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	case bool:
+		return fmt.Sprint(x)
+	}
+	encoded, _ := json.Marshal(v)
+	return string(encoded)
+}
+
+// 终端颜色，用于 plan、diff 等变更预览
+const (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
+)
+
+// colorize 在标准输出为终端且未设置 NO_COLOR 时为文本加上颜色
+func colorize(color, s string) string {
+	if os.Getenv("NO_COLOR") != "" {
+		return s
+	}
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return s
+	}
+	return color + s + colorReset
+}
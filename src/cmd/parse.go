@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// flatEntry 扁平化后的一个配置项
+type flatEntry struct {
+	Key   string
+	Value string
+	Type  string // string / number / boolean / json
+}
+
+// detectFormat 根据文件名推断格式
+func detectFormat(file string) (string, error) {
+	base := strings.ToLower(filepath.Base(file))
+	switch ext := filepath.Ext(base); {
+	case ext == ".env" || base == ".env" || strings.HasPrefix(base, ".env."):
+		return "dotenv", nil
+	case ext == ".json":
+		return "json", nil
+	case ext == ".yaml" || ext == ".yml":
+		return "yaml", nil
+	case ext == ".properties":
+		return "properties", nil
+	}
+	return "", fmt.Errorf("cannot detect the format of %s, use --format dotenv|json|yaml|properties", file)
+}
+
+// parseEntries 按格式解析文件内容并扁平化为配置项，结果按键排序
+func parseEntries(format string, content []byte) ([]flatEntry, error) {
+	var entries []flatEntry
+	switch format {
+	case "dotenv":
+		pairs, err := parseDotenv(content)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pairs {
+			entries = append(entries, flatEntry{Key: p[0], Value: p[1], Type: "string"})
+		}
+	case "properties":
+		pairs, err := parseProperties(content)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pairs {
+			entries = append(entries, flatEntry{Key: p[0], Value: p[1], Type: "string"})
+		}
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		var doc interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return flattenDocument(doc)
+	case "yaml":
+		var doc interface{}
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		return flattenDocument(doc)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (supported: dotenv, json, yaml, properties)", format)
+	}
+	return sortEntries(entries)
+}
+
+// flattenDocument 将解析出的 JSON/YAML 文档展开为按键排序的配置项
+func flattenDocument(doc interface{}) ([]flatEntry, error) {
+	var entries []flatEntry
+	if err := flatten("", doc, &entries); err != nil {
+		return nil, err
+	}
+	return sortEntries(entries)
+}
+
+// sortEntries 按键排序，并拒绝重复的键
+func sortEntries(entries []flatEntry) ([]flatEntry, error) {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Key == entries[i-1].Key {
+			return nil, fmt.Errorf("duplicate key: %s", entries[i].Key)
+		}
+	}
+	return entries, nil
+}
+
+// flatten 将嵌套对象展开为点分隔的键，数组保存为 JSON 字符串
+func flatten(prefix string, node interface{}, entries *[]flatEntry) error {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && prefix != "" {
+			*entries = append(*entries, flatEntry{Key: prefix, Value: "{}", Type: "json"})
+		}
+		for key, child := range v {
+			if err := flatten(joinKey(prefix, key), child, entries); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			converted[fmt.Sprint(key)] = child
+		}
+		return flatten(prefix, converted, entries)
+	default:
+		if prefix == "" {
+			return fmt.Errorf("top level must be an object")
+		}
+		value, valueType, err := scalarValue(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prefix, err)
+		}
+		*entries = append(*entries, flatEntry{Key: prefix, Value: value, Type: valueType})
+	}
+	return nil
+}
+
+// scalarValue 将解析出的值转换为字符串及对应的 config_type
+func scalarValue(v interface{}) (string, string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", "string", nil
+	case string:
+		return x, "string", nil
+	case bool:
+		return strconv.FormatBool(x), "boolean", nil
+	case json.Number:
+		return x.String(), "number", nil
+	case int:
+		return strconv.Itoa(x), "number", nil
+	case int64:
+		return strconv.FormatInt(x, 10), "number", nil
+	case uint64:
+		return strconv.FormatUint(x, 10), "number", nil
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), "number", nil
+	case time.Time:
+		// YAML 中未加引号的日期会被解析为时间，尽量还原为原始写法
+		if x.Equal(x.Truncate(24*time.Hour)) && x.Location() == time.UTC {
+			return x.Format("2006-01-02"), "string", nil
+		}
+		return x.Format(time.RFC3339), "string", nil
+	case []interface{}:
+		encoded, err := json.Marshal(normalizeJSON(x))
+		if err != nil {
+			return "", "", err
+		}
+		return string(encoded), "json", nil
+	}
+	return "", "", fmt.Errorf("unsupported value type %T", v)
+}
+
+// normalizeJSON 将 YAML 解析出的 map[interface{}]interface{} 转换为可 JSON 编码的结构
+func normalizeJSON(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for key, child := range x {
+			m[fmt.Sprint(key)] = normalizeJSON(child)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for key, child := range x {
+			m[key] = normalizeJSON(child)
+		}
+		return m
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, child := range x {
+			out[i] = normalizeJSON(child)
+		}
+		return out
+	}
+	return v
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// parseDotenv 解析 .env 文件，支持 export 前缀、单引号（字面值）、双引号（支持转义及多行）和行尾注释
+func parseDotenv(content []byte) ([][2]string, error) {
+	var pairs [][2]string
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=value", i+1)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single quote", i+1)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			// 双引号值可以跨行，直到遇到未转义的双引号
+			raw := value[1:]
+			for !hasClosingQuote(raw) {
+				i++
+				if i >= len(lines) {
+					return nil, fmt.Errorf("unterminated double quote for %s", key)
+				}
+				raw += "\n" + lines[i]
+			}
+			value = unescapeDotenv(raw[:closingQuote(raw)])
+		default:
+			if idx := strings.Index(value, " #"); idx >= 0 {
+				value = strings.TrimSpace(value[:idx])
+			}
+		}
+		pairs = append(pairs, [2]string{key, value})
+	}
+	return pairs, nil
+}
+
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == '"' {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasClosingQuote(s string) bool {
+	return closingQuote(s) >= 0
+}
+
+func unescapeDotenv(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// parseProperties 解析 Java properties 文件：支持 = : 或空白分隔、# ! 注释、行尾 \ 续行以及 \uXXXX 转义
+func parseProperties(content []byte) ([][2]string, error) {
+	var pairs [][2]string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	var logical strings.Builder
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical.Len() == 0 && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+		// 行尾奇数个反斜杠表示续行
+		trailing := len(line) - len(strings.TrimRight(line, `\`))
+		if trailing%2 == 1 {
+			logical.WriteString(line[:len(line)-1])
+			continue
+		}
+		logical.WriteString(line)
+		key, value := splitProperty(logical.String())
+		logical.Reset()
+		pairs = append(pairs, [2]string{unescapeProperty(key), unescapeProperty(value)})
+	}
+	if logical.Len() > 0 {
+		key, value := splitProperty(logical.String())
+		pairs = append(pairs, [2]string{unescapeProperty(key), unescapeProperty(value)})
+	}
+	return pairs, scanner.Err()
+}
+
+func splitProperty(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return line[:i], strings.TrimLeft(line[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			rest := strings.TrimLeft(line[i:], " \t\f")
+			if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+				rest = strings.TrimLeft(rest[1:], " \t\f")
+			}
+			return line[:i], rest
+		}
+	}
+	return line, ""
+}
+
+func unescapeProperty(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    [][2]string
+		wantErr bool
+	}{
+		{"plain", "A=1\nB=two\n", [][2]string{{"A", "1"}, {"B", "two"}}, false},
+		{"comments and blanks", "# comment\n\nA=1 # trailing\n", [][2]string{{"A", "1"}}, false},
+		{"export prefix", "export A=1", [][2]string{{"A", "1"}}, false},
+		{"spaces around =", "A = 1", [][2]string{{"A", "1"}}, false},
+		{"empty value", "A=", [][2]string{{"A", ""}}, false},
+		{"value with =", "URL=a=b", [][2]string{{"URL", "a=b"}}, false},
+		{"single quotes are literal", `A='x # $HOME \n'`, [][2]string{{"A", `x # $HOME \n`}}, false},
+		{"double quote escapes", `A="say \"hi\"\tand\\ \$HOME"`, [][2]string{{"A", "say \"hi\"\tand\\ $HOME"}}, false},
+		{"multiline double quotes", "A=\"line1\nline2\"\nB=2", [][2]string{{"A", "line1\nline2"}, {"B", "2"}}, false},
+		{"crlf", "A=1\r\nB=2\r\n", [][2]string{{"A", "1"}, {"B", "2"}}, false},
+		{"missing =", "A", nil, true},
+		{"unterminated single quote", "A='x", nil, true},
+		{"unterminated double quote", "A=\"x\nB=2", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDotenv([]byte(tt.content))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: parseDotenv error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseDotenv = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    [][2]string
+	}{
+		{"separators", "a=1\nb:2\nc 3\nd = 4\ne : 5", [][2]string{{"a", "1"}, {"b", "2"}, {"c", "3"}, {"d", "4"}, {"e", "5"}}},
+		{"comments", "# c\n! c\n\n  a=1", [][2]string{{"a", "1"}}},
+		{"empty value", "a=\nb", [][2]string{{"a", ""}, {"b", ""}}},
+		{"continuation", "a=one, \\\n    two", [][2]string{{"a", "one, two"}}},
+		{"escaped backslash is not continuation", "a=x\\\\\nb=2", [][2]string{{"a", `x\`}, {"b", "2"}}},
+		{"continuation at end of file", "a=x\\", [][2]string{{"a", "x"}}},
+		{"escaped separator in key", `a\=b=c`, [][2]string{{"a=b", "c"}}},
+		{"escapes", `a=tab\there\nnew \u00e9`, [][2]string{{"a", "tab\there\nnew é"}}},
+		{"value keeps separators", "url=jdbc:mysql://h:3306/db", [][2]string{{"url", "jdbc:mysql://h:3306/db"}}},
+	}
+	for _, tt := range tests {
+		got, err := parseProperties([]byte(tt.content))
+		if err != nil {
+			t.Errorf("%s: parseProperties error = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseProperties = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// formatDotenv 的输出应能被 parseDotenv 还原
+func TestDotenvRoundTrip(t *testing.T) {
+	values := []string{
+		"", "plain", "hello world", "a#b", "$HOME", "it's", "it's $HOME", "it's `date`",
+		`say "hi" it's`, "line1\nline2", "tab\there it's", `back\slash it's`, `C:\path`, "cr\r\nlf",
+	}
+	var vars []envVar
+	for i, value := range values {
+		vars = append(vars, envVar{Name: "V" + string(rune('A'+i)), Value: value})
+	}
+	pairs, err := parseDotenv([]byte(formatDotenv(vars)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != len(vars) {
+		t.Fatalf("parsed %d pairs, want %d", len(pairs), len(vars))
+	}
+	for i, v := range vars {
+		if pairs[i] != [2]string{v.Name, v.Value} {
+			t.Errorf("round trip of %q = %q", v.Value, pairs[i][1])
+		}
+	}
+}
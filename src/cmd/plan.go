@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+	"gopkg.in/yaml.v3"
+)
+
+// desiredFile dem plan/apply 读取的期望状态文件，描述一个作用域的全部配置：
+//
+//	project: app                # project/env/module 可省略，默认使用 -p/-e/-m
+//	env: prod
+//	module: api
+//	keys:
+//	  db.host: db1.internal
+//	  db.port: 5432             # 值的类型与 import 相同：数字为 number，数组为 json
+//	  cache:                    # 嵌套对象展开为 cache.ttl
+//	    ttl: 60
+type desiredFile struct {
+	Project string                 `yaml:"project"`
+	Env     string                 `yaml:"env"`
+	Module  string                 `yaml:"module"`
+	Keys    map[string]interface{} `yaml:"keys"`
+}
+
+// plan 中的变更类型
+const (
+	ActionAdd    = "add"
+	ActionChange = "change"
+	ActionDelete = "delete"
+)
+
+// planChange 期望状态与 config_master 之间的一项差异
+type planChange struct {
+	Action   string  `json:"action"`
+	Key      string  `json:"config_key"`
+	OldValue *string `json:"old_value,omitempty"`
+	NewValue *string `json:"new_value,omitempty"`
+	OldType  string  `json:"old_type,omitempty"`
+	NewType  string  `json:"new_type,omitempty"`
+
+	entry    flatEntry           // 期望的值，add/change 时使用
+	existing models.ConfigMaster // 库中的配置，change/delete 时使用
+}
+
+// planResult plan/apply 的结构化输出
+type planResult struct {
+	Project   string       `json:"project"`
+	Env       string       `json:"env"`
+	Module    string       `json:"module"`
+	Changes   []planChange `json:"changes"`
+	Unmanaged []string     `json:"unmanaged"` // 作用域中存在而文件中没有、且未使用 --prune 的键
+}
+
+// plan 结构化输出时 table/csv 的列
+var planOutputColumns = []string{"action", "config_key", "old_value", "new_value", "old_type", "new_type"}
+
+// HandlePlanCommand 显示将作用域变为 file 所描述状态所需的变更，不写入数据库
+// prune 为 true 时文件中没有的键计划删除；加密的值除非 reveal 为 true 否则以 ****** 显示
+func HandlePlanCommand(project, env, module, file string, prune, reveal bool) {
+	plan := buildPlan(project, env, module, file, prune)
+	if !reveal {
+		maskPlan(&plan)
+	}
+	if structuredOutput() {
+		printStructured(plan, plan.Changes, planOutputColumns)
+		return
+	}
+	printPlan(plan, prune)
+}
+
+// HandleApplyCommand 显示计划并在确认后于一个事务中执行全部变更
+// 结构化输出时不询问确认（需要 autoApprove），执行后输出与 plan 相同结构的已执行变更
+func HandleApplyCommand(project, env, module, file string, prune, reveal, autoApprove bool, operator string) {
+	if structuredOutput() && !autoApprove {
+		fmt.Fprintf(os.Stderr, "--output %s requires --auto-approve\n", outputFormat)
+		os.Exit(1)
+	}
+	plan := buildPlan(project, env, module, file, prune)
+	if !reveal {
+		maskPlan(&plan)
+	}
+	if !structuredOutput() {
+		printPlan(plan, prune)
+	}
+	if len(plan.Changes) == 0 {
+		if structuredOutput() {
+			printStructured(plan, plan.Changes, planOutputColumns)
+		}
+		return
+	}
+
+	if !autoApprove {
+		fmt.Printf("Apply these changes to %s/%s/%s? (Y/N): ", plan.Project, plan.Env, plan.Module)
+		var confirm string
+		fmt.Scanln(&confirm)
+		if confirm != "Y" && confirm != "y" {
+			fmt.Println("Apply cancelled.")
+			return
+		}
+	}
+
+	currentTime := time.Now()
+	var configs []models.ConfigMaster
+	var deleteIDs []int64
+	for _, change := range plan.Changes {
+		switch change.Action {
+		case ActionDelete:
+			deleteIDs = append(deleteIDs, change.existing.ID)
+		case ActionChange:
+			// 保留别名、说明等文件中没有描述的字段
+			config := change.existing
+			config.ConfigValue = constant.ToStrPtr(change.entry.Value)
+			config.ConfigType = constant.ToStrPtr(change.entry.Type)
+			config.UpdatedTime = constant.ToTimePtr(currentTime)
+			config.UpdatedBy = constant.ToStrPtr(operator)
+			configs = append(configs, config)
+		case ActionAdd:
+			alias := generateDefaultAlias(change.Key)
+			config := models.ConfigMaster{
+				Project:     constant.ToStrPtr(plan.Project),
+				Env:         constant.ToStrPtr(plan.Env),
+				Module:      constant.ToStrPtr(plan.Module),
+				ConfigKey:   constant.ToStrPtr(change.Key),
+				ConfigValue: constant.ToStrPtr(change.entry.Value),
+				ConfigAlias: constant.ToStrPtr(alias),
+				AutoAlias:   constant.ToStrPtr(alias),
+				ConfigType:  constant.ToStrPtr(change.entry.Type),
+				IsEncrypted: constant.ToIntPtr(1),
+				CreatedTime: constant.ToTimePtr(currentTime),
+				UpdatedTime: constant.ToTimePtr(currentTime),
+				UpdatedBy:   constant.ToStrPtr(operator),
+			}
+			if rule, err := schemaRule(plan.Project, plan.Module, change.Key); err != nil {
+				log.Fatalf("Failed to query schema of %s: %v", change.Key, err)
+			} else if rule != nil {
+				config.Description = rule.Description
+			}
+			configs = append(configs, config)
+		}
+	}
+	if err := db.ApplyConfigs(configs, deleteIDs, operator); err != nil {
+		log.Fatalf("Failed to apply changes: %v", err)
+	}
+	if structuredOutput() {
+		printStructured(plan, plan.Changes, planOutputColumns)
+		return
+	}
+	fmt.Printf("Applied %d changes to %s/%s/%s\n", len(plan.Changes), plan.Project, plan.Env, plan.Module)
+}
+
+// buildPlan 读取期望状态文件并与作用域中的配置比较，文件中的 project/env/module 优先于 -p/-e/-m
+// 文件无法解析或值不符合约束时直接退出
+func buildPlan(project, env, module, file string, prune bool) planResult {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", file, err)
+	}
+	var doc desiredFile
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid desired state %s: %v\n", file, err)
+		os.Exit(1)
+	}
+	if doc.Project != "" {
+		project = doc.Project
+	}
+	if doc.Env != "" {
+		env = doc.Env
+	}
+	if doc.Module != "" {
+		module = doc.Module
+	}
+
+	var entries []flatEntry
+	if len(doc.Keys) > 0 {
+		if entries, err = flattenDocument(doc.Keys); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid desired state %s: %v\n", file, err)
+			os.Exit(1)
+		}
+	}
+	invalid, err := checkEntries(project, env, module, entries)
+	if err != nil {
+		log.Fatalf("Failed to query schema: %v", err)
+	}
+	if invalid > 0 {
+		fmt.Printf("%d keys do not match the schema of %s/%s\n", invalid, project, module)
+		os.Exit(1)
+	}
+
+	current, err := queryMaster([]string{"project=?", "env=?", "module=?"}, []interface{}{project, env, module})
+	if err != nil {
+		log.Fatalf("Failed to query existing config: %v", err)
+	}
+	existing := map[string]models.ConfigMaster{}
+	for _, c := range current {
+		existing[constant.SafeStr(c.ConfigKey)] = c
+	}
+
+	plan := planResult{Project: project, Env: env, Module: module, Changes: []planChange{}, Unmanaged: []string{}}
+	desired := map[string]bool{}
+	for _, entry := range entries {
+		desired[entry.Key] = true
+		old, ok := existing[entry.Key]
+		if !ok {
+			plan.Changes = append(plan.Changes, planChange{Action: ActionAdd, Key: entry.Key,
+				NewValue: constant.ToStrPtr(entry.Value), NewType: entry.Type, entry: entry})
+			continue
+		}
+		if constant.SafeStr(old.ConfigValue) == entry.Value && constant.SafeStr(old.ConfigType) == entry.Type {
+			continue
+		}
+		plan.Changes = append(plan.Changes, planChange{Action: ActionChange, Key: entry.Key,
+			OldValue: old.ConfigValue, NewValue: constant.ToStrPtr(entry.Value),
+			OldType: constant.SafeStr(old.ConfigType), NewType: entry.Type, entry: entry, existing: old})
+	}
+	// current 已按键排序，删除的键排在新增和修改之后
+	for _, c := range current {
+		key := constant.SafeStr(c.ConfigKey)
+		if desired[key] {
+			continue
+		}
+		if !prune {
+			plan.Unmanaged = append(plan.Unmanaged, key)
+			continue
+		}
+		plan.Changes = append(plan.Changes, planChange{Action: ActionDelete, Key: key,
+			OldValue: c.ConfigValue, OldType: constant.SafeStr(c.ConfigType), existing: c})
+	}
+	return plan
+}
+
+// maskedValue 加密配置在未使用 --reveal 时显示的值
+const maskedValue = "******"
+
+func isEncrypted(c models.ConfigMaster) bool {
+	return c.IsEncrypted != nil && *c.IsEncrypted == 1
+}
+
+// maskPlan 隐藏加密配置的新旧值，新增的配置总是加密保存；只修改显示的值，不影响执行的变更
+func maskPlan(plan *planResult) {
+	for i := range plan.Changes {
+		change := &plan.Changes[i]
+		if change.Action != ActionAdd && !isEncrypted(change.existing) {
+			continue
+		}
+		if change.OldValue != nil {
+			change.OldValue = constant.ToStrPtr(maskedValue)
+		}
+		if change.NewValue != nil {
+			change.NewValue = constant.ToStrPtr(maskedValue)
+		}
+	}
+}
+
+func printPlan(plan planResult, prune bool) {
+	scope := fmt.Sprintf("%s/%s/%s", plan.Project, plan.Env, plan.Module)
+	if len(plan.Changes) == 0 {
+		fmt.Printf("No changes. %s matches the desired state.\n", scope)
+	} else {
+		fmt.Printf("dem will perform the following changes in %s:\n\n", scope)
+	}
+
+	counts := map[string]int{}
+	for _, change := range plan.Changes {
+		counts[change.Action]++
+		switch change.Action {
+		case ActionAdd:
+			fmt.Println(colorize(colorGreen, fmt.Sprintf("  + %s = %q (%s)", change.Key, *change.NewValue, change.NewType)))
+		case ActionDelete:
+			fmt.Println(colorize(colorRed, fmt.Sprintf("  - %s = %q", change.Key, constant.SafeStr(change.OldValue))))
+		case ActionChange:
+			line := fmt.Sprintf("  ~ %s = %q -> %q", change.Key, constant.SafeStr(change.OldValue), *change.NewValue)
+			// 按库中的值比较，隐藏后的值总是相同
+			if constant.SafeStr(change.existing.ConfigValue) == change.entry.Value {
+				line = fmt.Sprintf("  ~ %s", change.Key)
+			}
+			if change.OldType != change.NewType {
+				line += fmt.Sprintf(" (%s -> %s)", change.OldType, change.NewType)
+			}
+			fmt.Println(colorize(colorYellow, line))
+		}
+	}
+	if len(plan.Changes) > 0 {
+		fmt.Printf("\nPlan: %d to add, %d to change, %d to delete.\n", counts[ActionAdd], counts[ActionChange], counts[ActionDelete])
+	}
+	if len(plan.Unmanaged) > 0 && !prune {
+		fmt.Printf("%d keys in %s are not in the file and will be kept (use --prune to delete them)\n", len(plan.Unmanaged), scope)
+	}
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+)
+
+// HandlePromoteCommand 将 from 作用域中的配置复制到 to 作用域，from、to 的格式见 parseScopeArg
+// 目标中缺少的键新增，值或类型不同的键更新（onlyMissing 为 true 时保留），目标独有的键不受影响。
+// keys 不为空时只复制匹配的键；ignore 为按项目列出的应当各环境不同的键，始终跳过。
+// 预览差异并确认后在一个事务中写入，历史记录中的操作者为 operator
+func HandlePromoteCommand(project, module, from, to string, keys []string, ignore map[string][]string,
+	onlyMissing, reveal, yes bool, operator string) {
+	fromScope, err := parseScopeArg(from, project, module)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	toScope, err := parseScopeArg(to, project, module)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if fromScope == toScope {
+		fmt.Fprintf(os.Stderr, "Cannot promote %s to itself\n", strings.Join(fromScope[:], "/"))
+		os.Exit(1)
+	}
+	if _, err := matchKey(keys, ""); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	// 忽略列表取目标项目的设置，模式已在读取设置时检查
+	ignored := ignore[toScope[0]]
+
+	var skipped []string
+	entries, err := diffScopes(fromScope, toScope, func(key string) bool {
+		if matched, _ := matchKey(keys, key); len(keys) > 0 && !matched {
+			return false
+		}
+		if matched, _ := matchKey(ignored, key); matched {
+			skipped = append(skipped, key)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		log.Fatalf("Failed to compare %s and %s: %v", from, to, err)
+	}
+
+	source := strings.Join(fromScope[:], "/")
+	target := strings.Join(toScope[:], "/")
+	var promoted []diffEntry
+	var kept int
+	for _, entry := range entries {
+		switch {
+		case entry.Status == DiffOnlyLeft:
+			promoted = append(promoted, entry)
+		case entry.Status == DiffChanged && !onlyMissing:
+			promoted = append(promoted, entry)
+		case entry.Status == DiffChanged:
+			kept++
+		}
+	}
+
+	if len(skipped) > 0 {
+		fmt.Printf("Ignoring %d keys listed in [promote.ignore] for %s: %s\n", len(skipped), toScope[0], strings.Join(skipped, ", "))
+	}
+	if kept > 0 {
+		fmt.Printf("Keeping %d keys that already exist in %s (--only-missing)\n", kept, target)
+	}
+	if len(promoted) == 0 {
+		fmt.Printf("Nothing to promote from %s to %s.\n", source, target)
+		return
+	}
+
+	// 按约束检查写入目标的值，声明了约束的键使用约束中的类型
+	flat := make([]flatEntry, len(promoted))
+	for i, entry := range promoted {
+		flat[i] = flatEntry{Key: entry.Key, Value: constant.SafeStr(entry.left.ConfigValue), Type: constant.SafeStr(entry.left.ConfigType)}
+	}
+	invalid, err := checkEntries(toScope[0], toScope[1], toScope[2], flat)
+	if err != nil {
+		log.Fatalf("Failed to query schema: %v", err)
+	}
+	if invalid > 0 {
+		fmt.Printf("%d keys do not match the schema of %s/%s\n", invalid, toScope[0], toScope[2])
+		os.Exit(1)
+	}
+
+	fmt.Println(colorize(colorRed, "--- "+target))
+	fmt.Println(colorize(colorGreen, "+++ "+source))
+	added := 0
+	for _, entry := range promoted {
+		preview := entry
+		if !reveal {
+			maskDiffEntry(&preview)
+		}
+		// 预览以目标为旧值、来源为新值
+		preview.LeftValue, preview.RightValue = preview.RightValue, preview.LeftValue
+		preview.LeftType, preview.RightType = preview.RightType, preview.LeftType
+		if entry.Status == DiffOnlyLeft {
+			preview.Status = DiffOnlyRight
+			added++
+		}
+		fmt.Println(formatDiffEntry(preview))
+	}
+	fmt.Printf("Promote %d keys from %s to %s: %d to add, %d to update\n", len(promoted), source, target, added, len(promoted)-added)
+
+	if !yes {
+		fmt.Printf("Proceed? (Y/N): ")
+		var confirm string
+		fmt.Scanln(&confirm)
+		if confirm != "Y" && confirm != "y" {
+			fmt.Println("Promotion cancelled.")
+			return
+		}
+	}
+
+	currentTime := time.Now()
+	var configs []models.ConfigMaster
+	for i, entry := range promoted {
+		// 新增的键沿用来源的别名和说明，已存在的键保留目标原有的别名和说明
+		config := entry.left
+		if entry.Status == DiffChanged {
+			config = entry.right
+			config.IsEncrypted = entry.left.IsEncrypted
+		}
+		config.ID = 0
+		config.Project = constant.ToStrPtr(toScope[0])
+		config.Env = constant.ToStrPtr(toScope[1])
+		config.Module = constant.ToStrPtr(toScope[2])
+		config.ConfigValue = constant.ToStrPtr(flat[i].Value)
+		config.ConfigType = constant.ToStrPtr(flat[i].Type)
+		config.CreatedTime = constant.ToTimePtr(currentTime)
+		config.UpdatedTime = constant.ToTimePtr(currentTime)
+		config.UpdatedBy = constant.ToStrPtr(operator)
+		configs = append(configs, config)
+	}
+	if err := db.AddConfigs(configs); err != nil {
+		log.Fatalf("Failed to promote config: %v", err)
+	}
+	fmt.Printf("Promoted %d keys from %s to %s\n", len(configs), source, target)
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+)
+
+// renderData 模板中 . 的值
+type renderData struct {
+	Project string
+	Env     string
+	Module  string
+}
+
+// renderer 渲染一个模板，记录渲染过程中缺失的配置，渲染结束后统一报告
+type renderer struct {
+	renderData
+	missing  []string
+	resolver *resolver // 为 nil 时使用原始值，不展开 ${...} 引用
+}
+
+// HandleRenderCommand 用作用域内的配置渲染 Go text/template 模板，input/output 为 - 时使用标准输入/输出
+// 模板中可用的函数见 funcs；任一配置缺失时不写出结果，并列出全部缺失的配置
+// 只展开模板中用到的配置的引用，raw 为 true 时不展开
+func HandleRenderCommand(project, env, module, input, output string, raw bool) {
+	var content []byte
+	var err error
+	if input == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(input)
+	}
+	if err != nil {
+		log.Fatalf("Failed to read template %s: %v", input, err)
+	}
+
+	r := &renderer{renderData: renderData{Project: project, Env: env, Module: module}}
+	if !raw {
+		r.resolver = newResolver("")
+	}
+	tmpl, err := template.New(filepath.Base(input)).Option("missingkey=error").Funcs(r.funcs()).Parse(string(content))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid template: %v\n", err)
+		os.Exit(1)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, r.renderData); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to render %s: %v\n", input, err)
+		os.Exit(1)
+	}
+	if len(r.missing) > 0 {
+		fmt.Fprintf(os.Stderr, "Failed to render %s, %d missing keys:\n", input, len(r.missing))
+		for _, m := range r.missing {
+			fmt.Fprintf(os.Stderr, "  %s\n", m)
+		}
+		os.Exit(1)
+	}
+
+	if output == "-" {
+		fmt.Print(out.String())
+		return
+	}
+	// 渲染结果可能包含明文配置，新建的文件仅允许当前用户读写；已存在的文件保留原权限
+	if err := os.WriteFile(output, []byte(out.String()), 0600); err != nil {
+		log.Fatalf("Failed to write %s: %v", output, err)
+	}
+	fmt.Printf("Rendered %s to %s\n", input, output)
+}
+
+// funcs 模板函数
+//
+//	dem "key" ["env" ["module"]]   读取配置，可指定其他环境/模块，缺失时报错
+//	optional "key" ["env" ["module"]] 读取配置，缺失时返回空字符串
+//	default "fallback" VALUE        VALUE 为空时返回 fallback，如 {{ optional "db.port" | default "3306" }}
+//	required "message" VALUE        VALUE 为空时报错
+//	toJson / fromJson / quote / squote / b64enc / b64dec / indent / upper / lower / trim / replace ...
+func (r *renderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"dem": func(key string, scope ...string) (string, error) {
+			value, found, err := r.lookup(key, scope)
+			if err == nil && !found {
+				r.missing = append(r.missing, r.describe(key, scope))
+			}
+			return value, err
+		},
+		"optional": func(key string, scope ...string) (string, error) {
+			value, _, err := r.lookup(key, scope)
+			return value, err
+		},
+		"default": func(fallback, value interface{}) interface{} {
+			if isEmpty(value) {
+				return fallback
+			}
+			return value
+		},
+		"required": func(message string, value interface{}) interface{} {
+			if isEmpty(value) {
+				r.missing = append(r.missing, message)
+			}
+			return value
+		},
+		"toJson": func(v interface{}) (string, error) {
+			encoded, err := json.Marshal(v)
+			return string(encoded), err
+		},
+		"fromJson": func(s string) (interface{}, error) {
+			var v interface{}
+			err := json.Unmarshal([]byte(s), &v)
+			return v, err
+		},
+		"quote":  strconv.Quote,
+		"squote": func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" },
+		"b64enc": func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec": func(s string) (string, error) {
+			decoded, err := base64.StdEncoding.DecodeString(s)
+			return string(decoded), err
+		},
+		"indent": func(spaces int, s string) string {
+			pad := strings.Repeat(" ", spaces)
+			return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       func(sep string, items []string) string { return strings.Join(items, sep) },
+	}
+}
+
+// lookup 与 get 一样按作用域层和三级查询逻辑查找配置，scope 依次覆盖 env 和 module
+// 匹配到多个不同的值时返回错误，需要在模板中指定环境或模块
+func (r *renderer) lookup(key string, scope []string) (string, bool, error) {
+	env, module := r.Env, r.Module
+	switch len(scope) {
+	case 2:
+		module = scope[1]
+		fallthrough
+	case 1:
+		env = scope[0]
+	case 0:
+	default:
+		return "", false, fmt.Errorf("dem %q: expected at most env and module after the key", key)
+	}
+
+	configs, _, err := lookupKey(r.Project, env, module, key)
+	if err != nil || len(configs) == 0 {
+		return "", false, err
+	}
+	if r.resolver != nil {
+		if err := r.resolver.resolveConfigs(configs); err != nil {
+			return "", false, err
+		}
+	}
+	value := constant.SafeStr(configs[0].ConfigValue)
+	for _, config := range configs[1:] {
+		if constant.SafeStr(config.ConfigValue) != value {
+			return "", false, fmt.Errorf("%s matches %d keys with different values, specify the env/module", r.describe(key, scope), len(configs))
+		}
+	}
+	return value, true, nil
+}
+
+// describe 以 project/env/module:key 的形式描述模板中引用的配置
+func (r *renderer) describe(key string, scope []string) string {
+	env, module := r.Env, r.Module
+	if len(scope) > 0 {
+		env = scope[0]
+	}
+	if len(scope) > 1 {
+		module = scope[1]
+	}
+	return fmt.Sprintf("%s/%s/%s:%s", r.Project, env, module, key)
+}
+
+func isEmpty(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return x == ""
+	case bool:
+		return !x
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+)
+
+// HandleRollbackCommand 将配置项恢复到某个历史版本
+// to 为历史记录 ID 或版本时间；to 为空时回退 steps 个版本。已删除的配置项同样可以恢复
+func HandleRollbackCommand(project, env, module string, verbose bool, key, to string, steps int, yes bool, operator string) {
+	history, err := queryHistory(project, env, module, key, "")
+	if err != nil {
+		log.Fatalf("Failed to query config history: %v", err)
+	}
+	if len(history) == 0 {
+		fmt.Fprintf(os.Stderr, "No history found for key: %s\n", key)
+		os.Exit(1)
+	}
+
+	target, err := selectRollbackTarget(history, to, steps)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if !yes {
+		fmt.Printf("Roll back '%s' [%s/%s/%s] to version %s (#%d)? (Y/N): ",
+			constant.SafeStr(target.ConfigKey), constant.SafeStr(target.Project), constant.SafeStr(target.Env),
+			constant.SafeStr(target.Module), formatTime(target.Version), target.ID)
+		var confirm string
+		fmt.Scanln(&confirm)
+		if confirm != "Y" && confirm != "y" {
+			fmt.Println("Rollback cancelled.")
+			return
+		}
+	}
+
+	currentTime := time.Now()
+	config := target.ConfigMaster
+	config.CreatedTime = constant.ToTimePtr(currentTime)
+	config.UpdatedTime = constant.ToTimePtr(currentTime)
+	config.UpdatedBy = constant.ToStrPtr(operator)
+	if err := db.AddConfig(config); err != nil {
+		log.Fatalf("Failed to roll back config: %v", err)
+	}
+
+	if verbose {
+		fmt.Printf("Configuration item rolled back successfully:\n")
+		fmt.Printf("  Project: %s\n", constant.SafeStr(config.Project))
+		fmt.Printf("  Environment: %s\n", constant.SafeStr(config.Env))
+		fmt.Printf("  Module: %s\n", constant.SafeStr(config.Module))
+		fmt.Printf("  Key: %s\n", constant.SafeStr(config.ConfigKey))
+		fmt.Printf("  Value: %s\n", constant.SafeStr(config.ConfigValue))
+		fmt.Printf("  Version: %s (#%d)\n", formatTime(target.Version), target.ID)
+	} else {
+		fmt.Printf("Rolled back: %s to %s\n", constant.SafeStr(config.ConfigKey), formatTime(target.Version))
+	}
+}
+
+// selectRollbackTarget 从新到旧排列的历史记录中选出要恢复的版本
+func selectRollbackTarget(history []models.ConfigHistory, to string, steps int) (*models.ConfigHistory, error) {
+	if to != "" {
+		if id, err := strconv.ParseInt(to, 10, 64); err == nil {
+			for i := range history {
+				if history[i].ID == id {
+					return &history[i], nil
+				}
+			}
+			return nil, fmt.Errorf("history entry #%d not found for this key", id)
+		}
+		version, err := parseTimeArg(to)
+		if err != nil {
+			return nil, err
+		}
+		// 同一秒内可能有多个版本，取最新的一个
+		for i := range history {
+			if history[i].Version != nil && history[i].Version.UTC().Format(dbTimeLayout) == version {
+				return &history[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no version %s found for this key, see 'dem history'", to)
+	}
+
+	// 按步数回退时历史必须属于同一个配置项，否则需要用 -p/-e/-m 缩小范围
+	first := history[0]
+	for _, h := range history[1:] {
+		if constant.SafeStr(h.Project) != constant.SafeStr(first.Project) ||
+			constant.SafeStr(h.Env) != constant.SafeStr(first.Env) ||
+			constant.SafeStr(h.Module) != constant.SafeStr(first.Module) ||
+			constant.SafeStr(h.ConfigKey) != constant.SafeStr(first.ConfigKey) {
+			return nil, fmt.Errorf("history matches several config items, narrow it down with -p/-e/-m or use --to <id>")
+		}
+	}
+	if steps < 1 {
+		return nil, fmt.Errorf("--steps must be at least 1")
+	}
+	if steps > len(history) {
+		return nil, fmt.Errorf("only %d versions available", len(history))
+	}
+	return &history[steps-1], nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+)
+
+// HandleRunCommand 将作用域内的配置作为环境变量注入子进程并执行 command
+// only 不为空时只注入配置键匹配其中任一模式（path.Match 语法，如 db.*）的配置
+// 信号与退出码由子进程直接承接，见 execCommand；raw 为 true 时不展开值中的 ${...} 引用
+func HandleRunCommand(project, env, module string, only []string, mapper KeyMapper, raw bool, command []string) {
+	// 先按 --only 过滤再展开引用，未注入的配置中无法展开的引用不影响执行
+	configs, err := scopeItems(project, env, module, true)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	configs, err = filterKeys(configs, only)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if !raw {
+		if err := newResolver("").resolveItems(configs); err != nil {
+			fmt.Fprintf(os.Stderr, "%v (use --raw to inject stored values)\n", err)
+			os.Exit(1)
+		}
+	}
+	vars, err := mapEnvVars(configs, mapper)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	environ := os.Environ()
+	for _, v := range vars {
+		environ = append(environ, v.Name+"="+v.Value)
+	}
+	log.Debug("Running %v with %d variables from %s/%s/%s", command, len(vars), project, env, module)
+
+	if err := execCommand(command, environ); err != nil {
+		fmt.Fprintf(os.Stderr, "dem: %v\n", err)
+		os.Exit(127)
+	}
+}
+
+// filterKeys 只保留配置键匹配任一模式的配置项，patterns 为空时不过滤
+func filterKeys(configs []ConfigItem, patterns []string) ([]ConfigItem, error) {
+	if len(patterns) == 0 {
+		return configs, nil
+	}
+	var filtered []ConfigItem
+	for _, config := range configs {
+		matched, err := matchKey(patterns, constant.SafeStr(config.ConfigKey))
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			filtered = append(filtered, config)
+		}
+	}
+	return filtered, nil
+}
+
+// matchKey 判断 key 是否匹配任一模式（path.Match 语法，如 db.*）
+func matchKey(patterns []string, key string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, key)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
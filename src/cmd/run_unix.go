@@ -0,0 +1,18 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// execCommand 用 command 替换当前进程（execve），子进程保持相同的 PID，
+// 因此发送给 dem 的信号直接到达子进程，退出码也就是子进程的退出码
+func execCommand(command []string, environ []string) error {
+	binary, err := exec.LookPath(command[0])
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(binary, command, environ)
+}
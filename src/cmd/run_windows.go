@@ -0,0 +1,43 @@
+//go:build windows
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+// execCommand 启动子进程并等待其结束：Windows 不支持 execve，
+// 因此将收到的中断信号转发给子进程，并以子进程的退出码退出
+func execCommand(command []string, environ []string) error {
+	child := exec.Command(command[0], command[1:]...)
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	child.Env = environ
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	if err := child.Start(); err != nil {
+		return err
+	}
+	go func() {
+		for sig := range signals {
+			child.Process.Signal(sig)
+		}
+	}()
+
+	err := child.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	} else if err != nil {
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
@@ -0,0 +1,357 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+	"gopkg.in/yaml.v3"
+)
+
+// schemaFile dem schema apply 读取的约束文件：
+//
+//	project: app
+//	module: api                 # 可省略，默认使用 -m
+//	envs: [dev, prod]           # 必须存在各键的环境，可在键中覆盖；省略表示所有环境
+//	keys:
+//	  db.host:
+//	    description: Database host
+//	    pattern: '^[a-z0-9.-]+$'
+//	  db.port:
+//	    type: number
+//	    min: 1
+//	    max: 65535
+//	  log.level:
+//	    enum: [debug, info, warn, error]
+//	    required: false
+type schemaFile struct {
+	Project string               `yaml:"project"`
+	Module  string               `yaml:"module"`
+	Envs    []string             `yaml:"envs"`
+	Keys    map[string]schemaKey `yaml:"keys"`
+}
+
+type schemaKey struct {
+	Type        string   `yaml:"type"`     // 默认 string
+	Required    *bool    `yaml:"required"` // 默认 true
+	Envs        []string `yaml:"envs"`
+	Enum        []string `yaml:"enum"`
+	Pattern     string   `yaml:"pattern"`
+	Min         *float64 `yaml:"min"`
+	Max         *float64 `yaml:"max"`
+	Description string   `yaml:"description"`
+}
+
+// schema show 结构化输出时 table/csv 的列
+var schemaOutputColumns = []string{"config_key", "config_type", "required", "envs", "enum", "pattern", "min", "max", "description"}
+
+// HandleSchemaApplyCommand 读取约束文件，替换 project/module 原有的全部约束
+// 文件中的 project、module 优先于 -p、-m
+func HandleSchemaApplyCommand(project, module, file, operator string) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", file, err)
+	}
+	doc, rules, err := parseSchema(content)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid schema %s: %v\n", file, err)
+		os.Exit(1)
+	}
+	if doc.Project != "" {
+		project = doc.Project
+	}
+	if doc.Module != "" {
+		module = doc.Module
+	}
+	if project == "" || project == "default" {
+		fmt.Fprintf(os.Stderr, "Invalid schema %s: set project in the file or with -p\n", file)
+		os.Exit(1)
+	}
+
+	current, err := db.QuerySchema(project, module, "")
+	if err != nil {
+		log.Fatalf("Failed to query schema: %v", err)
+	}
+	old := map[string]bool{}
+	for _, rule := range current {
+		old[rule.ConfigKey] = true
+	}
+	for _, rule := range rules {
+		if old[rule.ConfigKey] {
+			delete(old, rule.ConfigKey)
+		} else {
+			fmt.Printf("+    %s (%s)\n", rule.ConfigKey, rule.ConfigType)
+		}
+	}
+	var removed []string
+	for key := range old {
+		removed = append(removed, key)
+	}
+	sort.Strings(removed)
+	for _, key := range removed {
+		fmt.Printf("-    %s\n", key)
+	}
+
+	if err := db.ReplaceSchema(project, module, rules, operator); err != nil {
+		log.Fatalf("Failed to apply schema: %v", err)
+	}
+	fmt.Printf("Applied schema for %s/%s: %d keys, %d removed\n", project, module, len(rules), len(removed))
+}
+
+// HandleSchemaShowCommand 显示 project/module 的约束
+func HandleSchemaShowCommand(project, module string) {
+	rules, err := db.QuerySchema(project, module, "")
+	if err != nil {
+		log.Fatalf("Failed to query schema: %v", err)
+	}
+	if structuredOutput() {
+		if rules == nil {
+			rules = []models.ConfigSchema{}
+		}
+		printStructured(rules, rules, schemaOutputColumns)
+		return
+	}
+	if len(rules) == 0 {
+		fmt.Printf("No schema for %s/%s, define one with 'dem schema apply <file>'\n", project, module)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tTYPE\tREQUIRED\tCONSTRAINTS\tDESCRIPTION")
+	for _, rule := range rules {
+		required := "no"
+		if rule.Required {
+			required = "all envs"
+			if len(rule.Envs) > 0 {
+				required = strings.Join(rule.Envs, ",")
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", rule.ConfigKey, rule.ConfigType, required,
+			describeConstraints(rule), singleLine(constant.SafeStr(rule.Description)))
+	}
+	w.Flush()
+}
+
+// parseSchema 解析并检查约束文件，返回按键排序的约束
+func parseSchema(content []byte) (schemaFile, []models.ConfigSchema, error) {
+	var doc schemaFile
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil {
+		return doc, nil, err
+	}
+	if len(doc.Keys) == 0 {
+		return doc, nil, fmt.Errorf("no keys declared")
+	}
+
+	var rules []models.ConfigSchema
+	for key, k := range doc.Keys {
+		rule := models.ConfigSchema{
+			ConfigKey:  key,
+			ConfigType: k.Type,
+			Required:   k.Required == nil || *k.Required,
+			Envs:       k.Envs,
+			Min:        k.Min,
+			Max:        k.Max,
+		}
+		if rule.ConfigType == "" {
+			rule.ConfigType = TypeString
+		}
+		if rule.Envs == nil {
+			rule.Envs = doc.Envs
+		}
+		if k.Pattern != "" {
+			if _, err := regexp.Compile(k.Pattern); err != nil {
+				return doc, nil, fmt.Errorf("%s: invalid pattern: %v", key, err)
+			}
+			rule.Pattern = constant.ToStrPtr(k.Pattern)
+		}
+		if k.Description != "" {
+			rule.Description = constant.ToStrPtr(k.Description)
+		}
+		if (rule.Min != nil || rule.Max != nil) && rule.ConfigType != TypeNumber {
+			return doc, nil, fmt.Errorf("%s: min and max require type number", key)
+		}
+		if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+			return doc, nil, fmt.Errorf("%s: min %s is greater than max %s", key, formatFloat(*rule.Min), formatFloat(*rule.Max))
+		}
+		// 取值列表中的每一项也必须符合类型，boolean 规范化为 true/false
+		for _, value := range k.Enum {
+			normalized, err := validateValue(rule.ConfigType, value)
+			if err != nil {
+				return doc, nil, fmt.Errorf("%s: enum: %v", key, err)
+			}
+			rule.Enum = append(rule.Enum, normalized)
+		}
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ConfigKey < rules[j].ConfigKey })
+	return doc, rules, nil
+}
+
+// checkRule 检查 value 是否符合约束，返回规范化后的值
+func checkRule(rule models.ConfigSchema, value string) (string, error) {
+	normalized, err := validateValue(rule.ConfigType, value)
+	if err != nil {
+		return "", err
+	}
+	if len(rule.Enum) > 0 && !inEnum(rule, normalized) {
+		return "", fmt.Errorf("%q is not one of %s", value, strings.Join(rule.Enum, ", "))
+	}
+	if rule.Pattern != nil {
+		re, err := regexp.Compile(*rule.Pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern %q: %v", *rule.Pattern, err)
+		}
+		if !re.MatchString(normalized) {
+			return "", fmt.Errorf("%q does not match %s", value, *rule.Pattern)
+		}
+	}
+	if rule.ConfigType == TypeNumber && (rule.Min != nil || rule.Max != nil) {
+		n, err := strconv.ParseFloat(normalized, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a number", value)
+		}
+		if rule.Min != nil && n < *rule.Min {
+			return "", fmt.Errorf("%s is below the minimum %s", normalized, formatFloat(*rule.Min))
+		}
+		if rule.Max != nil && n > *rule.Max {
+			return "", fmt.Errorf("%s is above the maximum %s", normalized, formatFloat(*rule.Max))
+		}
+	}
+	return normalized, nil
+}
+
+// inEnum 判断规范化后的值是否在取值列表中，number 按数值比较
+func inEnum(rule models.ConfigSchema, value string) bool {
+	for _, allowed := range rule.Enum {
+		if allowed == value {
+			return true
+		}
+		if rule.ConfigType == TypeNumber {
+			a, errA := strconv.ParseFloat(allowed, 64)
+			b, errB := strconv.ParseFloat(value, 64)
+			if errA == nil && errB == nil && a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// schemaRule 返回 project/module 中 key 的约束，没有约束时返回 nil
+func schemaRule(project, module, key string) (*models.ConfigSchema, error) {
+	rules, err := db.QuerySchema(project, module, key)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	return &rules[0], nil
+}
+
+// checkNewValue 检查即将写入 project/env/module 的值，rule 为 nil 时只检查类型
+// 含 ${...} 引用的值仅在有约束或类型不是 string 时按展开后的结果检查，返回值保留引用原样保存；
+// 引用无法展开（目标不存在或循环引用）时，有约束的键拒绝写入（force 为 true 时除外），没有约束的键只在标准错误输出提示
+func checkNewValue(project, env, module, key, configType, value string, rule *models.ConfigSchema, force bool) (string, error) {
+	check := func(v string) (string, error) {
+		if rule != nil {
+			return checkRule(*rule, v)
+		}
+		return validateValue(configType, v)
+	}
+	if !strings.Contains(value, "${") || (rule == nil && configType == TypeString) {
+		return check(value)
+	}
+	resolved, err := newResolver("").resolve(models.ConfigMaster{
+		Project: constant.ToStrPtr(project), Env: constant.ToStrPtr(env), Module: constant.ToStrPtr(module),
+		ConfigKey: constant.ToStrPtr(key), ConfigValue: constant.ToStrPtr(value),
+	})
+	if err != nil {
+		if rule != nil && !force {
+			return "", fmt.Errorf("cannot check it against the schema: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s is saved without checking: %v\n", key, err)
+		return value, nil
+	}
+	if _, err := check(resolved); err != nil {
+		return "", fmt.Errorf("%v (expanded from %q)", err, value)
+	}
+	return value, nil
+}
+
+// checkEntries 按 project/module 的约束检查即将写入 project/env/module 的配置项：
+// 声明了约束的键改用约束中的类型和规范化后的值，不符合约束的键逐个输出，返回其数量
+func checkEntries(project, env, module string, entries []flatEntry) (int, error) {
+	rules, err := db.QuerySchema(project, module, "")
+	if err != nil {
+		return 0, err
+	}
+	byKey := map[string]*models.ConfigSchema{}
+	for i := range rules {
+		byKey[rules[i].ConfigKey] = &rules[i]
+	}
+	invalid := 0
+	for i, entry := range entries {
+		rule, ok := byKey[entry.Key]
+		if !ok {
+			continue
+		}
+		value, err := checkNewValue(project, env, module, entry.Key, rule.ConfigType, entry.Value, rule, false)
+		if err != nil {
+			invalid++
+			fmt.Printf("!    %s: %v\n", entry.Key, err)
+			continue
+		}
+		entries[i].Value, entries[i].Type = value, rule.ConfigType
+	}
+	return invalid, nil
+}
+
+// requiredIn 判断约束是否要求键在 env 中存在
+func requiredIn(rule models.ConfigSchema, env string) bool {
+	if !rule.Required {
+		return false
+	}
+	if len(rule.Envs) == 0 {
+		return true
+	}
+	for _, e := range rule.Envs {
+		if e == env {
+			return true
+		}
+	}
+	return false
+}
+
+// describeConstraints 以一行文本描述约束的取值限制
+func describeConstraints(rule models.ConfigSchema) string {
+	var parts []string
+	if len(rule.Enum) > 0 {
+		parts = append(parts, "one of "+strings.Join(rule.Enum, "|"))
+	}
+	if rule.Pattern != nil {
+		parts = append(parts, "matches "+*rule.Pattern)
+	}
+	if rule.Min != nil {
+		parts = append(parts, ">= "+formatFloat(*rule.Min))
+	}
+	if rule.Max != nil {
+		parts = append(parts, "<= "+formatFloat(*rule.Max))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
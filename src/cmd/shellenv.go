@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 支持的 shell
+const (
+	ShellBash       = "bash"
+	ShellZsh        = "zsh"
+	ShellFish       = "fish"
+	ShellPowerShell = "powershell"
+)
+
+// HandleEnvCommand 输出设置作用域内全部配置的 shell 语句，供 eval 使用
+// unset 为 true 时输出对应的取消设置语句，用于在不同环境之间切换；raw 为 true 时不展开值中的 ${...} 引用
+func HandleEnvCommand(project, env, module, shell string, unset, raw bool, mapper KeyMapper) {
+	shell, err := ResolveShell(shell)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	configs, err := scopeItems(project, env, module, raw || unset)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v (use --raw to print stored values)\n", err)
+		os.Exit(1)
+	}
+	vars, err := mapEnvVars(configs, mapper)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	for _, v := range vars {
+		if unset {
+			fmt.Println(unsetStatement(shell, v.Name))
+		} else {
+			fmt.Println(exportStatement(shell, v.Name, v.Value))
+		}
+	}
+}
+
+// ResolveShell 校验 shell 名称，为空时根据 $SHELL 推断，无法推断时使用 bash
+func ResolveShell(shell string) (string, error) {
+	if shell == "" {
+		shell = strings.TrimSuffix(filepath.Base(os.Getenv("SHELL")), ".exe")
+	}
+	switch shell {
+	case ShellBash, ShellZsh, ShellFish, ShellPowerShell:
+		return shell, nil
+	case "pwsh":
+		return ShellPowerShell, nil
+	case "", ".", "sh":
+		return ShellBash, nil
+	}
+	return "", fmt.Errorf("unsupported shell: %s (supported: bash, zsh, fish, powershell)", shell)
+}
+
+// exportStatement 返回在 shell 中设置环境变量的语句
+func exportStatement(shell, name, value string) string {
+	switch shell {
+	case ShellFish:
+		return fmt.Sprintf("set -gx %s %s;", name, shellQuote(shell, value))
+	case ShellPowerShell:
+		return fmt.Sprintf("$env:%s = %s", name, shellQuote(shell, value))
+	}
+	return fmt.Sprintf("export %s=%s;", name, shellQuote(shell, value))
+}
+
+// shellQuote 按 shell 的单引号规则引用字符串
+func shellQuote(shell, value string) string {
+	switch shell {
+	case ShellFish:
+		// fish 单引号内只有 \' 和 \\ 是转义
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+	case ShellPowerShell:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	// bash/zsh 单引号内没有转义，' 需要先结束引号再用 \' 拼接
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// unsetStatement 返回在 shell 中取消环境变量的语句
+func unsetStatement(shell, name string) string {
+	switch shell {
+	case ShellFish:
+		return fmt.Sprintf("set -e %s;", name)
+	case ShellPowerShell:
+		return fmt.Sprintf("Remove-Item Env:%s -ErrorAction SilentlyContinue", name)
+	}
+	return fmt.Sprintf("unset %s;", name)
+}
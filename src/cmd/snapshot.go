@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+)
+
+// queryConfigsAt 还原某一时刻的配置状态
+//
+// config_history 中 version 记录的是一个状态被替换（更新或删除）的时间，因此对每个配置项：
+// version > at 的最早一条历史记录即为 at 时刻生效的状态；没有这样的记录时取 config_master 中的当前值。
+// 若该状态的 created_time 晚于 at，说明配置项在 at 时刻尚不存在。
+// conditions 同时作用于 config_master 和 config_history，结果按 project, env, module, config_key 排序。
+func queryConfigsAt(conditions []string, params []interface{}, at string) ([]models.ConfigMaster, error) {
+	atTime, err := time.Parse(dbTimeLayout, at)
+	if err != nil {
+		return nil, err
+	}
+
+	where := append(append([]string{}, conditions...), "version > ?")
+	history, err := scanHistory("SELECT "+historyColumns+" FROM config_history WHERE "+strings.Join(where, " AND ")+
+		" ORDER BY project, env, module, config_key, version ASC, id ASC", append(append([]interface{}{}, params...), at)...)
+	if err != nil {
+		return nil, err
+	}
+
+	states := map[string]models.ConfigMaster{}
+	for _, h := range history {
+		id := configIdentity(h.ConfigMaster)
+		if _, ok := states[id]; !ok {
+			states[id] = h.ConfigMaster
+		}
+	}
+
+	current, err := queryMaster(conditions, params)
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range current {
+		id := configIdentity(config)
+		if _, ok := states[id]; !ok {
+			states[id] = config
+		}
+	}
+
+	var configs []models.ConfigMaster
+	for _, config := range states {
+		if config.CreatedTime != nil && config.CreatedTime.After(atTime) {
+			continue
+		}
+		configs = append(configs, config)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configIdentity(configs[i]) < configIdentity(configs[j])
+	})
+	return configs, nil
+}
+
+// queryMaster 按条件查询 config_master 中的当前配置并解密
+func queryMaster(conditions []string, params []interface{}) ([]models.ConfigMaster, error) {
+	query := `SELECT id, project, env, module, config_key, config_alias, auto_alias, config_value,
+		config_type, description, is_encrypted, sort_order, created_time, updated_time, updated_by FROM config_master`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY project, env, module, config_key"
+
+	rows, err := db.DB.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var configs []models.ConfigMaster
+	for rows.Next() {
+		var c models.ConfigMaster
+		err := rows.Scan(
+			&c.ID, &c.Project, &c.Env, &c.Module, &c.ConfigKey, &c.ConfigAlias, &c.AutoAlias, &c.ConfigValue,
+			&c.ConfigType, &c.Description, &c.IsEncrypted, &c.SortOrder, &c.CreatedTime, &c.UpdatedTime, &c.UpdatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.DecryptConfig(&c); err != nil {
+			return nil, err
+		}
+		configs = append(configs, c)
+	}
+	return configs, rows.Err()
+}
+
+// configIdentity 配置项的唯一标识 (project, env, module, config_key)，可用于排序
+func configIdentity(c models.ConfigMaster) string {
+	return strings.Join([]string{constant.SafeStr(c.Project), constant.SafeStr(c.Env), constant.SafeStr(c.Module), constant.SafeStr(c.ConfigKey)}, "\x00")
+}
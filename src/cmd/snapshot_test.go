@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/secret"
+)
+
+// openTestDB 在临时目录中创建数据库并按文件名顺序执行 sql 目录中的全部脚本，
+// 然后依次执行 stmts 写入测试数据
+func openTestDB(t *testing.T, stmts ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(secret.PassphraseEnv, "test passphrase")
+	secret.SetKeyFilePath(filepath.Join(dir, "master.key"))
+	t.Cleanup(func() { secret.SetKeyFilePath("") })
+
+	if err := db.InitDB(filepath.Join(dir, "dem.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+
+	files, err := filepath.Glob(filepath.Join("..", "sql", "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.DB.Exec(string(content)); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+	for _, stmt := range stmts {
+		if _, err := db.DB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+func TestQueryConfigsAt(t *testing.T) {
+	// a：10:00 创建为 v1，11:00 改为 v2，12:00 删除
+	// b：11:30 创建
+	// c：10:00 创建为 c1，10:30 删除，11:45 重新创建为 c2
+	openTestDB(t,
+		`INSERT INTO config_master (project, env, module, config_key, config_value, is_encrypted, created_time, updated_time)
+			VALUES ('p', 'dev', 'm', 'b', 'b1', 0, '2024-01-01 11:30:00', '2024-01-01 11:30:00'),
+			       ('p', 'dev', 'm', 'c', 'c2', 0, '2024-01-01 11:45:00', '2024-01-01 11:45:00')`,
+		`INSERT INTO config_history (project, env, module, config_key, config_value, is_encrypted, created_time, updated_time, version)
+			VALUES ('p', 'dev', 'm', 'a', 'v1', 0, '2024-01-01 10:00:00', '2024-01-01 10:00:00', '2024-01-01 11:00:00'),
+			       ('p', 'dev', 'm', 'a', 'v2', 0, '2024-01-01 10:00:00', '2024-01-01 11:00:00', '2024-01-01 12:00:00'),
+			       ('p', 'dev', 'm', 'c', 'c1', 0, '2024-01-01 10:00:00', '2024-01-01 10:00:00', '2024-01-01 10:30:00')`,
+	)
+
+	tests := []struct {
+		at   string
+		want map[string]string
+	}{
+		{"2024-01-01 09:00:00", map[string]string{}},
+		{"2024-01-01 10:15:00", map[string]string{"a": "v1", "c": "c1"}},
+		{"2024-01-01 11:00:00", map[string]string{"a": "v2"}},
+		{"2024-01-01 11:15:00", map[string]string{"a": "v2"}},
+		{"2024-01-01 11:50:00", map[string]string{"a": "v2", "b": "b1", "c": "c2"}},
+		{"2024-01-01 12:30:00", map[string]string{"b": "b1", "c": "c2"}},
+	}
+	for _, tt := range tests {
+		configs, err := queryConfigsAt([]string{"project=?", "env=?"}, []interface{}{"p", "dev"}, tt.at)
+		if err != nil {
+			t.Fatalf("queryConfigsAt(%s): %v", tt.at, err)
+		}
+		got := map[string]string{}
+		for _, config := range configs {
+			got[constant.SafeStr(config.ConfigKey)] = constant.SafeStr(config.ConfigValue)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("queryConfigsAt(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 数据库中的时间由 CURRENT_TIMESTAMP 写入，格式为 UTC 的 "YYYY-MM-DD HH:MM:SS"
+const dbTimeLayout = "2006-01-02 15:04:05"
+
+// 命令行接受的时间格式，按本地时区解析
+var timeArgLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseTimeArg 解析命令行中的时间参数，返回可直接与数据库时间列比较的字符串
+// 支持绝对时间（本地时区）以及相对时间，如 30m、12h、7d（表示多久之前）
+func parseTimeArg(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil {
+			return time.Now().AddDate(0, 0, -days).UTC().Format(dbTimeLayout), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d).UTC().Format(dbTimeLayout), nil
+	}
+	for _, layout := range timeArgLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.UTC().Format(dbTimeLayout), nil
+		}
+	}
+	return "", fmt.Errorf("invalid time %q, use e.g. \"2006-01-02 15:04:05\", \"2006-01-02\" or a duration like 12h, 7d", value)
+}
+
+// formatTime 以本地时区显示数据库中的时间
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Local().Format(dbTimeLayout)
+}
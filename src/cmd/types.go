@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config_type 支持的取值
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeJSON    = "json"
+	TypeYAML    = "yaml"
+)
+
+var numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// validateValue 检查 value 是否符合 configType，返回规范化后的值
+// boolean 统一保存为 true/false，其余类型保持原样
+func validateValue(configType, value string) (string, error) {
+	switch configType {
+	case TypeString:
+		return value, nil
+	case TypeNumber:
+		// 与 JSON 数字的写法一致，保证导出为 JSON 时仍是合法的数字
+		if !numberPattern.MatchString(strings.TrimSpace(value)) {
+			return "", fmt.Errorf("%q is not a number", value)
+		}
+		return strings.TrimSpace(value), nil
+	case TypeBoolean:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("%q is not a boolean, use true or false", value)
+		}
+		return strconv.FormatBool(b), nil
+	case TypeJSON:
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return "", fmt.Errorf("invalid JSON: %v", err)
+		}
+		return value, nil
+	case TypeYAML:
+		var v interface{}
+		if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+			return "", fmt.Errorf("invalid YAML: %v", err)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown type %q, use string, number, boolean, json or yaml", configType)
+}
+
+// typedValue 将配置值转换为对应类型的值，用于 JSON/YAML 输出
+// 未设置类型或值与类型不符时按字符串处理
+func typedValue(configType, value string) interface{} {
+	switch configType {
+	case TypeNumber:
+		if _, err := validateValue(TypeNumber, value); err == nil {
+			return json.Number(strings.TrimSpace(value))
+		}
+	case TypeBoolean:
+		if b, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+			return b
+		}
+	case TypeJSON:
+		if json.Valid([]byte(value)) {
+			return json.RawMessage(value)
+		}
+	case TypeYAML:
+		var v interface{}
+		if err := yaml.Unmarshal([]byte(value), &v); err == nil {
+			return normalizeJSON(v)
+		}
+	}
+	return value
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+)
+
+// validate 发现的问题类型
+const (
+	ProblemMissing = "missing" // 必须存在的键在各作用域层中均不存在
+	ProblemInvalid = "invalid" // 展开引用后的值不符合约束
+	ProblemExtra   = "extra"   // 作用域中存在约束未声明的键，仅提示，不导致校验失败
+)
+
+// validationIssue validate 发现的一个问题
+type validationIssue struct {
+	Env     string `json:"env"`
+	Key     string `json:"config_key"`
+	Problem string `json:"problem"`
+	Message string `json:"message"`
+	Source  string `json:"source,omitempty"` // 被检查的值所在的 project/env/module
+}
+
+// validationResult validate 的结构化输出
+type validationResult struct {
+	Project string            `json:"project"`
+	Module  string            `json:"module"`
+	Envs    []string          `json:"envs"`
+	Keys    int               `json:"keys"` // 约束声明的键数
+	Valid   bool              `json:"valid"`
+	Issues  []validationIssue `json:"issues"`
+}
+
+// validate 结构化输出时 table/csv 的列
+var validateOutputColumns = []string{"env", "config_key", "problem", "message", "source"}
+
+// HandleValidateCommand 按 project/module 的约束检查各环境的配置
+// 必须存在的键按 get 的作用域层回退查找，值展开引用后检查；有缺失或不合法的键时以状态码 1 退出
+// env 为 default 时检查约束中列出的所有环境，未列出时检查项目中已有的环境
+func HandleValidateCommand(project, env, module string) {
+	rules, err := db.QuerySchema(project, module, "")
+	if err != nil {
+		log.Fatalf("Failed to query schema: %v", err)
+	}
+	if len(rules) == 0 {
+		fmt.Fprintf(os.Stderr, "No schema for %s/%s, define one with 'dem schema apply <file>'\n", project, module)
+		os.Exit(1)
+	}
+	envs, err := validationEnvs(project, env, rules)
+	if err != nil {
+		log.Fatalf("Failed to query environments: %v", err)
+	}
+
+	result := validationResult{Project: project, Module: module, Envs: envs, Keys: len(rules), Valid: true, Issues: []validationIssue{}}
+	for _, e := range envs {
+		issues, err := validateScope(project, e, module, rules)
+		if err != nil {
+			log.Fatalf("Failed to validate %s/%s/%s: %v", project, e, module, err)
+		}
+		result.Issues = append(result.Issues, issues...)
+	}
+	for _, issue := range result.Issues {
+		if issue.Problem != ProblemExtra {
+			result.Valid = false
+		}
+	}
+
+	if structuredOutput() {
+		printStructured(result, result.Issues, validateOutputColumns)
+	} else {
+		printValidationResult(result)
+	}
+	if !result.Valid {
+		os.Exit(1)
+	}
+}
+
+// validationEnvs 返回需要检查的环境
+func validationEnvs(project, env string, rules []models.ConfigSchema) ([]string, error) {
+	if env != "" && env != "default" {
+		return []string{env}, nil
+	}
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		for _, e := range rule.Envs {
+			seen[e] = true
+		}
+	}
+	if len(seen) == 0 {
+		rows, err := db.DB.Query("SELECT DISTINCT env FROM config_master WHERE project = ? AND env IS NOT NULL AND env != 'default'", project)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var e string
+			if err := rows.Scan(&e); err != nil {
+				return nil, err
+			}
+			seen[e] = true
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if len(seen) == 0 {
+		return []string{"default"}, nil
+	}
+	var envs []string
+	for e := range seen {
+		envs = append(envs, e)
+	}
+	sort.Strings(envs)
+	return envs, nil
+}
+
+// validateScope 检查一个环境：约束中的键按作用域层查找，作用域本身中未声明的键记为 extra
+func validateScope(project, env, module string, rules []models.ConfigSchema) ([]validationIssue, error) {
+	var issues []validationIssue
+	r := newResolver("")
+	declared := map[string]bool{}
+	for _, rule := range rules {
+		declared[rule.ConfigKey] = true
+		config, found, err := findSchemaKey(project, env, module, rule.ConfigKey)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			if requiredIn(rule, env) {
+				issues = append(issues, validationIssue{Env: env, Key: rule.ConfigKey, Problem: ProblemMissing,
+					Message: fmt.Sprintf("required in %s", env)})
+			}
+			continue
+		}
+		source := fmt.Sprintf("%s/%s/%s", constant.SafeStr(config.Project), constant.SafeStr(config.Env), constant.SafeStr(config.Module))
+		value, err := r.resolve(config)
+		if err == nil {
+			_, err = checkRule(rule, value)
+		}
+		if err != nil {
+			issues = append(issues, validationIssue{Env: env, Key: rule.ConfigKey, Problem: ProblemInvalid, Message: err.Error(), Source: source})
+		}
+	}
+
+	current, err := queryMaster([]string{"project=?", "env=?", "module=?"}, []interface{}{project, env, module})
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range current {
+		if key := constant.SafeStr(config.ConfigKey); !declared[key] {
+			issues = append(issues, validationIssue{Env: env, Key: key, Problem: ProblemExtra,
+				Message: "not declared in the schema", Source: fmt.Sprintf("%s/%s/%s", project, env, module)})
+		}
+	}
+	return issues, nil
+}
+
+// findSchemaKey 按作用域层查找 config_key 等于 key 的配置，不匹配别名
+func findSchemaKey(project, env, module, key string) (models.ConfigMaster, bool, error) {
+	for _, layer := range scopeLayers(project, env, module) {
+		configs, err := queryMaster([]string{"project=?", "env=?", "module=?", "config_key=?"},
+			[]interface{}{layer[0], layer[1], layer[2], key})
+		if err != nil {
+			return models.ConfigMaster{}, false, err
+		}
+		if len(configs) > 0 {
+			return configs[0], true, nil
+		}
+	}
+	return models.ConfigMaster{}, false, nil
+}
+
+func printValidationResult(result validationResult) {
+	for _, env := range result.Envs {
+		scope := fmt.Sprintf("%s/%s/%s", result.Project, env, result.Module)
+		counts := map[string]int{}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, issue := range result.Issues {
+			if issue.Env != env {
+				continue
+			}
+			counts[issue.Problem]++
+			message := singleLine(issue.Message)
+			if issue.Source != "" && issue.Source != scope {
+				message += " (from " + issue.Source + ")"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", issue.Problem, issue.Key, message)
+		}
+		if counts[ProblemMissing]+counts[ProblemInvalid]+counts[ProblemExtra] == 0 {
+			fmt.Printf("%s: ok, %d keys checked\n", scope, result.Keys)
+			continue
+		}
+		fmt.Printf("%s:\n", scope)
+		w.Flush()
+		var summary []string
+		for _, problem := range []string{ProblemMissing, ProblemInvalid, ProblemExtra} {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[problem], problem))
+		}
+		fmt.Printf("  %s\n", strings.Join(summary, ", "))
+	}
+}
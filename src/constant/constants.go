@@ -2,6 +2,7 @@ package constant
 
 import (
 	"os"
+	"os/user"
 	"path/filepath"
 	"time"
 )
@@ -28,6 +29,22 @@ const (
 
 	// 数据文件位置
 	DBFileName = "dem_config.db"
+
+	// 主密钥文件名
+	KeyFileName = "master.key"
+
+	// 设置文件与日志文件名
+	SettingsFileName = "config.toml"
+	LogFileName      = "dem.log"
+
+	// 目录级上下文文件名，从当前目录逐级向上查找
+	ContextFileName = ".dem"
+
+	// 允许 shell 钩子自动加载的上下文文件列表
+	AllowFileName = "allow"
+
+	// 指定操作者身份的环境变量
+	OperatorEnv = "DEM_USER"
 )
 
 // 环境类型枚举
@@ -122,6 +139,24 @@ func GetProjectDir() string {
 	return demDir
 }
 
+// GetOperator 返回记录到 changed_by 的操作者身份
+// 优先级：--as 参数 > DEM_USER 环境变量 > 当前系统用户 > system
+func GetOperator(override string) string {
+	if override != "" {
+		return override
+	}
+	if name := os.Getenv(OperatorEnv); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "system"
+}
+
 // safeStr 解引用字符串指针，如果指针为nil则返回空字符串
 func SafeStr(s *string) string {
 	if s == nil {
@@ -130,6 +165,14 @@ func SafeStr(s *string) string {
 	return *s
 }
 
+// SafeInt 解引用整数指针，如果指针为nil则返回0
+func SafeInt(n *int) int {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
+
 func ToStrPtr(s string) *string {
 	return &s
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/zhangymPerson/dev-env-manage/src/constant"
 	"github.com/zhangymPerson/dev-env-manage/src/log"
 	"github.com/zhangymPerson/dev-env-manage/src/models"
+	"github.com/zhangymPerson/dev-env-manage/src/secret"
 )
 
 var DB *sql.DB
@@ -22,9 +23,14 @@ func InitDB(dataSourceName string) error {
 	return nil
 }
 
+// AddConfig 新增或更新单个配置项
 func AddConfig(config models.ConfigMaster) error {
-	db := DB
-	tx, err := db.Begin()
+	return AddConfigs([]models.ConfigMaster{config})
+}
+
+// AddConfigs 在一个事务中新增或更新多个配置项，任一失败则全部回滚
+func AddConfigs(configs []models.ConfigMaster) error {
+	tx, err := DB.Begin()
 	if err != nil {
 		log.Error("开始事务失败: %v", err)
 		return err
@@ -36,22 +42,46 @@ func AddConfig(config models.ConfigMaster) error {
 		}
 	}()
 
+	for _, config := range configs {
+		if err := addConfigTx(tx, config); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addConfigTx 在事务内新增或更新配置项：已存在相同 project/env/module/config_key 时更新，否则插入
+func addConfigTx(tx *sql.Tx, config models.ConfigMaster) error {
 	// 首先尝试查询是否已存在相同配置
 	var existingID int
-	err = tx.QueryRow(`
-		SELECT id FROM config_master 
+	var existingValue *string
+	var existingEncrypted *int
+	err := tx.QueryRow(`
+		SELECT id, config_value, is_encrypted FROM config_master 
 		WHERE project = ? AND env = ? AND module = ? AND config_key = ?`,
-		config.Project, config.Env, config.Module, config.ConfigKey).Scan(&existingID)
+		config.Project, config.Env, config.Module, config.ConfigKey).Scan(&existingID, &existingValue, &existingEncrypted)
+
+	if err != nil && err != sql.ErrNoRows {
+		// 查询过程中出现其他错误
+		log.Error("查询配置项失败: %v", err)
+		return err
+	}
+	exists := err == nil
+
+	if config.ConfigValue, err = sealValue(config, existingValue, existingEncrypted); err != nil {
+		log.Error("加密配置值失败: %v", err)
+		return err
+	}
 
-	if err == nil {
+	if exists {
 		// 配置已存在，执行更新操作
 		stmt, err := tx.Prepare(`
 			UPDATE config_master SET 
 				config_value = ?, config_alias = ?, auto_alias = ?, config_type = ?, 
-				is_encrypted = ?, description = ?, sort_order = ?, updated_time = CURRENT_TIMESTAMP
+				is_encrypted = ?, description = ?, sort_order = ?, updated_by = ?, updated_time = CURRENT_TIMESTAMP
 			WHERE id = ?`)
 		if err != nil {
-			tx.Rollback()
 			log.Error("准备更新语句失败: %v", err)
 			return err
 		}
@@ -59,30 +89,27 @@ func AddConfig(config models.ConfigMaster) error {
 
 		res, err := stmt.Exec(
 			config.ConfigValue, config.ConfigAlias, config.AutoAlias, config.ConfigType,
-			config.IsEncrypted, config.Description, config.SortOrder, existingID)
+			config.IsEncrypted, config.Description, config.SortOrder, config.UpdatedBy, existingID)
 
 		if err != nil {
-			tx.Rollback()
 			log.Error("执行更新失败: %v", err)
 			return err
 		}
 
 		if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
-			tx.Rollback()
 			return errors.New("没有行被更新")
 		}
 
 		log.Info("配置项已更新: 项目[%s] 环境[%s] 模块[%s] 键[%s]", constant.SafeStr(config.Project), constant.SafeStr(config.Env), constant.SafeStr(config.Module), constant.SafeStr(config.ConfigKey))
-	} else if err == sql.ErrNoRows {
+	} else {
 		// 配置不存在，执行插入操作
 		stmt, err := tx.Prepare(`
 			INSERT INTO config_master (
 				project, env, module, config_key, config_value, 
 				config_alias, auto_alias, config_type, is_encrypted,
-				description, sort_order, created_time, updated_time
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`)
+				description, sort_order, updated_by, created_time, updated_time
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`)
 		if err != nil {
-			tx.Rollback()
 			log.Error("准备插入语句失败: %v", err)
 			return err
 		}
@@ -91,26 +118,118 @@ func AddConfig(config models.ConfigMaster) error {
 		res, err := stmt.Exec(
 			config.Project, config.Env, config.Module, config.ConfigKey, config.ConfigValue,
 			config.ConfigAlias, config.AutoAlias, config.ConfigType, config.IsEncrypted,
-			config.Description, config.SortOrder)
+			config.Description, config.SortOrder, config.UpdatedBy)
 
 		if err != nil {
-			tx.Rollback()
 			log.Error("执行插入失败: %v", err)
 			return err
 		}
 
 		if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
-			tx.Rollback()
 			return errors.New("没有行被插入")
 		}
 
 		log.Info("配置项已新增: 项目[%s] 环境[%s] 模块[%s] 键[%s]", constant.SafeStr(config.Project), constant.SafeStr(config.Env), constant.SafeStr(config.Module), constant.SafeStr(config.ConfigKey))
-	} else {
-		// 查询过程中出现其他错误
+	}
+
+	return nil
+}
+
+// DeleteConfig 物理删除配置项，operator 会通过删除触发器记录到 config_history.changed_by
+func DeleteConfig(id int, operator string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Error("开始事务失败: %v", err)
+		return err
+	}
+	if err := deleteConfigTx(tx, int64(id), operator); err != nil {
 		tx.Rollback()
-		log.Error("查询配置项失败: %v", err)
 		return err
 	}
+	return tx.Commit()
+}
 
+// ApplyConfigs 在一个事务中新增或更新 configs 并删除 deleteIDs 对应的配置项，任一失败则全部回滚
+func ApplyConfigs(configs []models.ConfigMaster, deleteIDs []int64, operator string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Error("开始事务失败: %v", err)
+		return err
+	}
+	for _, config := range configs {
+		if err := addConfigTx(tx, config); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	for _, id := range deleteIDs {
+		if err := deleteConfigTx(tx, id, operator); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 	return tx.Commit()
 }
+
+// deleteConfigTx 在事务内物理删除配置项
+func deleteConfigTx(tx *sql.Tx, id int64, operator string) error {
+	// 先记录删除操作者，删除触发器从 OLD.updated_by 读取
+	// 更新触发器的 WHEN 条件不比较 updated_by（见 003_changed_by_operator.sql），
+	// 因此这次 UPDATE 不会产生 update 历史，删除只留下一条 delete 记录
+	if _, err := tx.Exec("UPDATE config_master SET updated_by = ? WHERE id = ?", operator, id); err != nil {
+		log.Error("记录操作者失败: %v", err)
+		return err
+	}
+
+	res, err := tx.Exec("DELETE FROM config_master WHERE id = ?", id)
+	if err != nil {
+		log.Error("执行删除失败: %v", err)
+		return err
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		return errors.New("没有行被删除")
+	}
+
+	log.Info("配置项已删除: id[%d] 操作者[%s]", id, operator)
+	return nil
+}
+
+// sealValue 在 is_encrypted=1 时加密配置值
+// 若新值与库中已加密的旧值相同，则沿用旧密文，避免触发器产生无意义的历史记录
+func sealValue(config models.ConfigMaster, existingValue *string, existingEncrypted *int) (*string, error) {
+	if config.ConfigValue == nil || config.IsEncrypted == nil || *config.IsEncrypted != 1 {
+		return config.ConfigValue, nil
+	}
+	if existingValue != nil && existingEncrypted != nil && *existingEncrypted == 1 && secret.IsEnvelope(*existingValue) {
+		if plain, err := secret.Decrypt(*existingValue); err == nil && plain == *config.ConfigValue {
+			return existingValue, nil
+		}
+	}
+	sealed, err := secret.Encrypt(*config.ConfigValue)
+	if err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// DecryptConfig 解密查询得到的配置值，is_encrypted 不为 1 时保持原样
+func DecryptConfig(config *models.ConfigMaster) error {
+	plain, err := DecryptValue(config.ConfigValue, config.IsEncrypted)
+	if err != nil {
+		return err
+	}
+	config.ConfigValue = plain
+	return nil
+}
+
+// DecryptValue 根据 is_encrypted 标志解密单个值
+func DecryptValue(value *string, isEncrypted *int) (*string, error) {
+	if value == nil || isEncrypted == nil || *isEncrypted != 1 {
+		return value, nil
+	}
+	plain, err := secret.Decrypt(*value)
+	if err != nil {
+		return nil, err
+	}
+	return &plain, nil
+}
@@ -0,0 +1,44 @@
+package db
+
+import "testing"
+
+// 删除前写入 updated_by 的 UPDATE 不应触发更新历史，删除只留下一条 delete 记录
+func TestDeleteConfigHistory(t *testing.T) {
+	openTestDB(t)
+	if err := AddConfig(newTestConfig("db.host", "localhost")); err != nil {
+		t.Fatal(err)
+	}
+	var id int
+	if err := DB.QueryRow("SELECT id FROM config_master WHERE config_key = 'db.host'").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteConfig(id, "remover"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := DB.Query("SELECT change_type, changed_by FROM config_history WHERE config_key = 'db.host'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	type entry struct{ changeType, changedBy string }
+	var got []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.changeType, &e.changedBy); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != 1 || got[0] != (entry{"delete", "remover"}) {
+		t.Errorf("history after delete = %+v, want one delete by remover", got)
+	}
+
+	var remaining int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM config_master").Scan(&remaining); err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 0 {
+		t.Errorf("config_master has %d rows after delete, want 0", remaining)
+	}
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/secret"
+)
+
+// encryptedTables 保存加密配置值的表
+var encryptedTables = []string{"config_master", "config_history"}
+
+// EncryptLegacy 在一个事务中加密早期版本以明文保存、但标记为 is_encrypted=1 的配置和历史记录，返回各表加密的行数
+// 没有这样的行时不开启事务，也不会创建主密钥
+func EncryptLegacy() (map[string]int, error) {
+	pending := 0
+	for _, table := range encryptedTables {
+		var n int
+		if err := DB.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table, legacyCondition)).Scan(&n); err != nil {
+			return nil, err
+		}
+		pending += n
+	}
+	if pending == 0 {
+		return nil, nil
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Error("开始事务失败: %v", err)
+		return nil, err
+	}
+	// 加密不是配置变更，暂时移除触发器避免写入历史记录
+	triggers, err := suspendTriggers(tx, "config_master")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, table := range encryptedTables {
+		n, err := encryptLegacyTable(tx, table)
+		if err != nil {
+			tx.Rollback()
+			log.Error("加密 %s 中的明文数据失败: %v", table, err)
+			return nil, err
+		}
+		counts[table] = n
+	}
+
+	for _, ddl := range triggers {
+		if _, err := tx.Exec(ddl); err != nil {
+			tx.Rollback()
+			log.Error("恢复触发器失败: %v", err)
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// legacyCondition 标记为加密但值不是加密格式（enc:v1:...）的行
+const legacyCondition = "is_encrypted = 1 AND config_value IS NOT NULL AND config_value NOT GLOB 'enc:v1:*'"
+
+func encryptLegacyTable(tx *sql.Tx, table string) (int, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT id, config_value FROM %s WHERE %s", table, legacyCondition))
+	if err != nil {
+		return 0, err
+	}
+	updates := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if secret.IsEnvelope(value) {
+			continue
+		}
+		sealed, err := secret.Encrypt(value)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("row %d: %w", id, err)
+		}
+		updates[id] = sealed
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	stmt, err := tx.Prepare(fmt.Sprintf("UPDATE %s SET config_value = ? WHERE id = ?", table))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for id, sealed := range updates {
+		if _, err := stmt.Exec(sealed, id); err != nil {
+			return 0, fmt.Errorf("row %d: %w", id, err)
+		}
+	}
+	return len(updates), nil
+}
+
+// suspendTriggers 删除指定表上的全部触发器，返回用于重建的 DDL
+func suspendTriggers(tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.Query("SELECT name, sql FROM sqlite_master WHERE type = 'trigger' AND tbl_name = ?", table)
+	if err != nil {
+		return nil, err
+	}
+	var names, ddls []string
+	for rows.Next() {
+		var name, ddl string
+		if err := rows.Scan(&name, &ddl); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+		ddls = append(ddls, ddl)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	for _, name := range names {
+		if _, err := tx.Exec(fmt.Sprintf("DROP TRIGGER %q", name)); err != nil {
+			return nil, err
+		}
+	}
+	return ddls, nil
+}
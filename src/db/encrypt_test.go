@@ -0,0 +1,112 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/zhangymPerson/dev-env-manage/src/secret"
+)
+
+// openTestDB 在临时目录中创建数据库并按文件名顺序执行 sql 目录中的全部脚本，
+// 主密钥文件同样放在临时目录中
+func openTestDB(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(secret.PassphraseEnv, "test passphrase")
+	t.Setenv(secret.NewPassphraseEnv, "")
+	secret.SetKeyFilePath(filepath.Join(dir, "master.key"))
+	t.Cleanup(func() { secret.SetKeyFilePath("") })
+
+	if err := InitDB(filepath.Join(dir, "dem.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DB.Close() })
+
+	files, err := filepath.Glob(filepath.Join("..", "sql", "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DB.Exec(string(content)); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+}
+
+// rowValues 返回表中各行的 config_value，按 id 排序
+func rowValues(t *testing.T, table string) []string {
+	t.Helper()
+	rows, err := DB.Query("SELECT config_value FROM " + table + " ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestEncryptLegacy(t *testing.T) {
+	openTestDB(t)
+	sealed, err := secret.Encrypt("already sealed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO config_master (project, env, module, config_key, config_value, is_encrypted) VALUES ('p', 'dev', 'm', 'legacy', 'plain', 1)`,
+		`INSERT INTO config_master (project, env, module, config_key, config_value, is_encrypted) VALUES ('p', 'dev', 'm', 'sealed', '` + sealed + `', 1)`,
+		`INSERT INTO config_master (project, env, module, config_key, config_value, is_encrypted) VALUES ('p', 'dev', 'm', 'clear', 'visible', 0)`,
+		`INSERT INTO config_history (project, env, module, config_key, config_value, is_encrypted) VALUES ('p', 'dev', 'm', 'legacy', 'old plain', 1)`,
+	} {
+		if _, err := DB.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		want map[string]int
+	}{
+		{"first run", map[string]int{"config_master": 1, "config_history": 1}},
+		{"second run", nil},
+	}
+	for _, tt := range tests {
+		counts, err := EncryptLegacy()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(counts) != len(tt.want) || counts["config_master"] != tt.want["config_master"] || counts["config_history"] != tt.want["config_history"] {
+			t.Errorf("%s: EncryptLegacy() = %v, want %v", tt.name, counts, tt.want)
+		}
+	}
+
+	master := rowValues(t, "config_master")
+	if !secret.IsEnvelope(master[0]) || master[1] != sealed || master[2] != "visible" {
+		t.Errorf("config_master values = %q", master)
+	}
+	if plain, err := secret.Decrypt(master[0]); err != nil || plain != "plain" {
+		t.Errorf("Decrypt(%q) = %q, %v", master[0], plain, err)
+	}
+	// 加密不是配置变更，不应产生新的历史记录
+	history := rowValues(t, "config_history")
+	if len(history) != 1 {
+		t.Fatalf("config_history has %d rows, want 1", len(history))
+	}
+	if plain, err := secret.Decrypt(history[0]); err != nil || plain != "old plain" {
+		t.Errorf("Decrypt(%q) = %q, %v", history[0], plain, err)
+	}
+}
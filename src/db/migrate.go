@@ -0,0 +1,89 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+)
+
+// Migration 一个版本化的数据库结构变更，对应 sql 目录下的 NNN_name.sql 文件
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// AppliedMigration schema_version 表中的一条记录
+type AppliedMigration struct {
+	Version     int
+	Name        string
+	AppliedTime time.Time
+}
+
+const schemaVersionDDL = `
+CREATE TABLE IF NOT EXISTS schema_version (
+    version INTEGER PRIMARY KEY, -- 迁移版本号（文件名前缀）
+    name VARCHAR(200), -- 迁移文件名
+    applied_time DATETIME DEFAULT CURRENT_TIMESTAMP -- 应用时间
+)`
+
+// AppliedMigrations 返回已应用的迁移，按版本号索引
+func AppliedMigrations() (map[int]AppliedMigration, error) {
+	if _, err := DB.Exec(schemaVersionDDL); err != nil {
+		return nil, err
+	}
+	rows, err := DB.Query("SELECT version, name, applied_time FROM schema_version ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]AppliedMigration{}
+	for rows.Next() {
+		var m AppliedMigration
+		if err := rows.Scan(&m.Version, &m.Name, &m.AppliedTime); err != nil {
+			return nil, err
+		}
+		applied[m.Version] = m
+	}
+	return applied, rows.Err()
+}
+
+// Migrate 按版本号顺序应用尚未执行的迁移，每个迁移在独立事务中执行
+// 返回本次应用的迁移
+func Migrate(migrations []Migration) ([]Migration, error) {
+	applied, err := AppliedMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_version: %w", err)
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := applyMigration(m); err != nil {
+			return done, fmt.Errorf("migration %s failed: %w", m.Name, err)
+		}
+		log.Info("已应用数据库迁移: %s", m.Name)
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func applyMigration(m Migration) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(m.SQL); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_version (version, name) VALUES (?, ?)", m.Version, m.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/secret"
+)
+
+// KeyUsage 统计 config_master 和 config_history 中各密钥 ID 引用的加密行数
+// 尚未加密的历史明文行以空字符串作为键
+func KeyUsage() (map[string]int, error) {
+	usage := map[string]int{}
+	for _, table := range encryptedTables {
+		rows, err := DB.Query(fmt.Sprintf("SELECT config_value FROM %s WHERE is_encrypted = 1 AND config_value IS NOT NULL", table))
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var value string
+			if err := rows.Scan(&value); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			usage[secret.KeyID(value)]++
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.Close()
+	}
+	return usage, nil
+}
+
+// ReencryptAll 在一个事务中将两张表中所有加密行改用当前主密钥加密
+// 已使用当前密钥的行会被跳过，因此中断后重复执行即可继续完成轮换
+func ReencryptAll() (map[string]int, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Error("开始事务失败: %v", err)
+		return nil, err
+	}
+
+	// 重新加密不是配置变更，暂时移除触发器避免写入历史记录；DDL 同样受事务保护
+	triggers, err := suspendTriggers(tx, "config_master")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, table := range encryptedTables {
+		n, err := reencryptTable(tx, table)
+		if err != nil {
+			tx.Rollback()
+			log.Error("重新加密 %s 失败: %v", table, err)
+			return nil, err
+		}
+		counts[table] = n
+	}
+
+	for _, ddl := range triggers {
+		if _, err := tx.Exec(ddl); err != nil {
+			tx.Rollback()
+			log.Error("恢复触发器失败: %v", err)
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func reencryptTable(tx *sql.Tx, table string) (int, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT id, config_value FROM %s WHERE is_encrypted = 1 AND config_value IS NOT NULL", table))
+	if err != nil {
+		return 0, err
+	}
+	updates := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		sealed, changed, err := secret.Reencrypt(value)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("row %d: %w", id, err)
+		}
+		if changed {
+			updates[id] = sealed
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	stmt, err := tx.Prepare(fmt.Sprintf("UPDATE %s SET config_value = ? WHERE id = ?", table))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for id, sealed := range updates {
+		if _, err := stmt.Exec(sealed, id); err != nil {
+			return 0, fmt.Errorf("row %d: %w", id, err)
+		}
+	}
+	return len(updates), nil
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+	"github.com/zhangymPerson/dev-env-manage/src/secret"
+)
+
+// newTestConfig 返回 p/dev/m 作用域中的加密配置项
+func newTestConfig(key, value string) models.ConfigMaster {
+	return models.ConfigMaster{
+		Project:     constant.ToStrPtr("p"),
+		Env:         constant.ToStrPtr("dev"),
+		Module:      constant.ToStrPtr("m"),
+		ConfigKey:   constant.ToStrPtr(key),
+		ConfigValue: constant.ToStrPtr(value),
+		ConfigType:  constant.ToStrPtr("string"),
+		IsEncrypted: constant.ToIntPtr(1),
+		SortOrder:   constant.ToIntPtr(0),
+		UpdatedBy:   constant.ToStrPtr("tester"),
+	}
+}
+
+func TestReencryptAll(t *testing.T) {
+	openTestDB(t)
+	for _, config := range []models.ConfigMaster{
+		newTestConfig("db.host", "old-host"),
+		newTestConfig("db.host", "new-host"), // 更新产生一条历史记录
+		newTestConfig("db.user", "admin"),
+	} {
+		if err := AddConfig(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldKey := secret.KeyID(rowValues(t, "config_master")[0])
+
+	newKey, err := secret.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newKey == oldKey {
+		t.Fatalf("Rotate() kept key %s", oldKey)
+	}
+
+	tests := []struct {
+		name string
+		want map[string]int
+	}{
+		{"rotation", map[string]int{"config_master": 2, "config_history": 1}},
+		{"rerun", map[string]int{"config_master": 0, "config_history": 0}},
+	}
+	for _, tt := range tests {
+		counts, err := ReencryptAll()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for table, want := range tt.want {
+			if counts[table] != want {
+				t.Errorf("%s: ReencryptAll() re-encrypted %d rows of %s, want %d", tt.name, counts[table], table, want)
+			}
+		}
+	}
+
+	want := map[string][]string{
+		"config_master":  {"new-host", "admin"},
+		"config_history": {"old-host"},
+	}
+	for table, plains := range want {
+		values := rowValues(t, table)
+		if len(values) != len(plains) {
+			t.Fatalf("%s has %d rows, want %d", table, len(values), len(plains))
+		}
+		for i, value := range values {
+			if id := secret.KeyID(value); id != newKey {
+				t.Errorf("%s row %d uses key %s, want %s", table, i, id, newKey)
+			}
+			if plain, err := secret.Decrypt(value); err != nil || plain != plains[i] {
+				t.Errorf("%s row %d: Decrypt = %q, %v, want %q", table, i, plain, err, plains[i])
+			}
+		}
+	}
+
+	usage, err := KeyUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[newKey] != 3 {
+		t.Errorf("KeyUsage() = %v, want only %s", usage, newKey)
+	}
+}
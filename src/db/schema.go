@@ -0,0 +1,105 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/models"
+)
+
+// ReplaceSchema 在一个事务中用 rules 替换 project/module 的全部约束
+func ReplaceSchema(project, module string, rules []models.ConfigSchema, operator string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Error("开始事务失败: %v", err)
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM config_schema WHERE project = ? AND module = ?", project, module); err != nil {
+		tx.Rollback()
+		log.Error("删除原有约束失败: %v", err)
+		return err
+	}
+	for _, rule := range rules {
+		envs, err := jsonList(rule.Envs)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		enum, err := jsonList(rule.Enum)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		required := 0
+		if rule.Required {
+			required = 1
+		}
+		_, err = tx.Exec(`
+			INSERT INTO config_schema (
+				project, module, config_key, config_type, required, envs, enum_values,
+				pattern, min_value, max_value, description, updated_by
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			project, module, rule.ConfigKey, rule.ConfigType, required, envs, enum,
+			rule.Pattern, rule.Min, rule.Max, rule.Description, operator)
+		if err != nil {
+			tx.Rollback()
+			log.Error("写入约束失败: %v", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// QuerySchema 返回 project/module 的约束，key 不为空时只返回该键的约束，按键排序
+func QuerySchema(project, module, key string) ([]models.ConfigSchema, error) {
+	query := `SELECT id, project, module, config_key, config_type, required, envs, enum_values,
+		pattern, min_value, max_value, description FROM config_schema WHERE project = ? AND module = ?`
+	params := []interface{}{project, module}
+	if key != "" {
+		query += " AND config_key = ?"
+		params = append(params, key)
+	}
+	rows, err := DB.Query(query+" ORDER BY config_key", params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.ConfigSchema
+	for rows.Next() {
+		var rule models.ConfigSchema
+		var required int
+		var envs, enum sql.NullString
+		if err := rows.Scan(&rule.ID, &rule.Project, &rule.Module, &rule.ConfigKey, &rule.ConfigType, &required,
+			&envs, &enum, &rule.Pattern, &rule.Min, &rule.Max, &rule.Description); err != nil {
+			return nil, err
+		}
+		rule.Required = required == 1
+		if envs.Valid && envs.String != "" {
+			if err := json.Unmarshal([]byte(envs.String), &rule.Envs); err != nil {
+				return nil, err
+			}
+		}
+		if enum.Valid && enum.String != "" {
+			if err := json.Unmarshal([]byte(enum.String), &rule.Enum); err != nil {
+				return nil, err
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// jsonList 将列表编码为 JSON 数组保存，空列表保存为 NULL
+func jsonList(values []string) (*string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	s := string(encoded)
+	return &s, nil
+}
@@ -1,89 +1,81 @@
 package src
 
 import (
-	"database/sql"
 	"embed"
-	"os"
-	"path/filepath"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
 
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
 	"github.com/zhangymPerson/dev-env-manage/src/log"
 )
 
 //go:embed sql/*
 var sqlFiles embed.FS
 
-// Init  初始化函数
+// 迁移文件命名规则：NNN_description.sql，NNN 为递增的版本号
+var migrationFileName = regexp.MustCompile(`^(\d+)_.+\.sql$`)
+
+// Init  初始化函数：按版本顺序将尚未应用的迁移升级到数据库
 func Init() {
-	// check sql directory
-	entries, err := sqlFiles.ReadDir("sql")
+	migrations, err := loadMigrations()
 	if err != nil {
-		log.Debug("Warning: No SQL files found or sql directory is empty")
-		return
+		log.Error("Failed to load migrations: %v", err)
+		panic(err)
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		content, err := sqlFiles.ReadFile(filepath.Join("sql", entry.Name()))
-		if err != nil {
-			log.Debug("Error reading SQL file %s: %v\\n", entry.Name(), err)
-			continue
-		}
-		log.Debug("File: %s\\nContent: %s\\n", entry.Name(), string(content))
+	applied, err := db.Migrate(migrations)
+	if err != nil {
+		log.Error("Failed to migrate database: %v", err)
+		panic(err)
 	}
-	dbPath := constant.GetDBFilePath()
-	if _, err := os.Stat(dbPath); err == nil {
-		log.Debug("Database file already exists")
-		return
+	if len(applied) > 0 {
+		log.Info("Database migrated to version %d", applied[len(applied)-1].Version)
 	} else {
-		log.Debug("Database file does not exist, creating...")
-		// execute sql files and create database and tables
-		if err := executeSQLFiles(dbPath); err != nil {
-			log.Error("Failed to execute SQL files: %v", err)
-			panic(err)
-		}
-		log.Info("Database created successfully at %s", dbPath)
+		log.Debug("Database schema is up to date")
 	}
 }
 
-// executeSQLFiles 执行 SQL 文件内容到数据库
-func executeSQLFiles(dbPath string) error {
-	// 打开数据库连接
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return err
-	}
-	defer db.Close()
-
-	// 检查 sql 目录是否存在文件
+// loadMigrations 读取内嵌 sql 目录中的迁移文件，按版本号排序
+func loadMigrations() ([]db.Migration, error) {
 	entries, err := sqlFiles.ReadDir("sql")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// 执行所有 SQL 文件
+	var migrations []db.Migration
+	seen := map[int]string{}
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
-		content, err := sqlFiles.ReadFile(filepath.Join("sql", entry.Name()))
-		if err != nil {
-			log.Debug("Error reading SQL file %s: %v\\n", entry.Name(), err)
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			log.Warning("Skipping SQL file with unexpected name: %s", entry.Name())
 			continue
 		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
 
-		sqlContent := string(content)
-		log.Debug("Executing SQL file: %s\\nContent: %s\\n", entry.Name(), sqlContent)
-
-		// 执行 SQL 语句
-		if _, err := db.Exec(sqlContent); err != nil {
-			log.Warning("Error executing SQL from file %s: %v", entry.Name(), err)
-			return err
+		content, err := sqlFiles.ReadFile(path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, err
 		}
+		log.Debug("Loaded migration %s\n", entry.Name())
+		migrations = append(migrations, db.Migration{Version: version, Name: entry.Name(), SQL: string(content)})
 	}
 
-	return nil
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
 }
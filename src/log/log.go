@@ -1,6 +1,7 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os"
@@ -8,10 +9,19 @@ import (
 	"runtime"
 )
 
+// Log levels, messages below the configured level are dropped
+const (
+	LevelDebug = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+)
+
 var (
-	IsDebug     = false  // Global debug flag
-	LogFilePath = ""     // Global log file path
-	logFile     *os.File // File handle for log file
+	IsDebug     = false     // Global debug flag
+	LogFilePath = ""        // Global log file path
+	Level       = LevelInfo // Global log level
+	logFile     *os.File    // File handle for log file
 )
 
 // getCallerInfo 获取调用者信息（文件名和行号）
@@ -42,12 +52,18 @@ func Debug(format string, v ...interface{}) {
 
 // Info logs informational messages
 func Info(format string, v ...interface{}) {
+	if !IsDebug && Level > LevelInfo {
+		return
+	}
 	file, line := getCallerInfo(2) // skip Info function and its caller
 	log.Printf("[%s:%d] [INFO] "+format, append([]interface{}{file, line}, v...)...)
 }
 
 // Warning logs warning messages
 func Warning(format string, v ...interface{}) {
+	if !IsDebug && Level > LevelWarning {
+		return
+	}
 	file, line := getCallerInfo(2) // skip Warning function and its caller
 	log.Printf("[%s:%d] [WARNING] "+format, append([]interface{}{file, line}, v...)...)
 }
@@ -112,3 +128,21 @@ func Close() error {
 func SetDebug() {
 	IsDebug = true
 }
+
+// SetLevel sets the log level by name: debug, info, warning or error
+func SetLevel(name string) error {
+	switch name {
+	case "debug":
+		Level = LevelDebug
+		IsDebug = true
+	case "info":
+		Level = LevelInfo
+	case "warning":
+		Level = LevelWarning
+	case "error":
+		Level = LevelError
+	default:
+		return fmt.Errorf("unknown log level: %s", name)
+	}
+	return nil
+}
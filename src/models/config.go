@@ -21,4 +21,33 @@ type ConfigMaster struct {
 
 	CreatedTime *time.Time `json:"created_time,omitempty"`
 	UpdatedTime *time.Time `json:"updated_time,omitempty"`
+	UpdatedBy   *string    `json:"updated_by,omitempty"`
+}
+
+// ConfigHistory 映射数据库表 config_history
+// 每行是 config_master 中某配置项被更新或删除前的快照
+type ConfigHistory struct {
+	ConfigMaster
+
+	Version    *time.Time `json:"version,omitempty"`
+	ChangedBy  *string    `json:"changed_by,omitempty"`
+	ChangeType *string    `json:"change_type,omitempty"` // update 或 delete
+
+}
+
+// ConfigSchema 映射数据库表 config_schema
+// 声明某项目、模块中一个配置键的约束，可为空的约束使用指针类型
+type ConfigSchema struct {
+	ID          int64    `json:"id"`
+	Project     string   `json:"project"`
+	Module      string   `json:"module"`
+	ConfigKey   string   `json:"config_key"`
+	ConfigType  string   `json:"config_type"`
+	Required    bool     `json:"required"`
+	Envs        []string `json:"envs,omitempty"` // 为空表示所有环境
+	Enum        []string `json:"enum,omitempty"`
+	Pattern     *string  `json:"pattern,omitempty"`
+	Min         *float64 `json:"min,omitempty"`
+	Max         *float64 `json:"max,omitempty"`
+	Description *string  `json:"description,omitempty"`
 }
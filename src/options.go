@@ -4,13 +4,17 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/zhangymPerson/dev-env-manage/src/cmd"
 	"github.com/zhangymPerson/dev-env-manage/src/constant"
+	"github.com/zhangymPerson/dev-env-manage/src/db"
 	"github.com/zhangymPerson/dev-env-manage/src/log"
+	"github.com/zhangymPerson/dev-env-manage/src/secret"
+	"github.com/zhangymPerson/dev-env-manage/src/settings"
 )
 
 type Config struct {
@@ -31,15 +35,14 @@ func Options(gitBranch string, gitCommit string) {
 	verbose := flag.Bool("v", false, "Enable verbose output")
 	version := flag.Bool("version", false, "Show version and build information")
 	alias := flag.String("alias", "", "Specify custom alias for the config")
-	// configPath := flag.String("config", "", "Specify config file path")
+	as := flag.String("as", "", "Record changes as this user (default: $DEM_USER or the OS user)")
+	output := flag.String("output", "", "Output format [text|json|yaml|table|csv] (default: text)")
+	configPath := flag.String("c", "", "Specify config file path (default: ~/.dem/config.toml)")
+	flag.StringVar(configPath, "config", "", "Specify config file path (default: ~/.dem/config.toml)")
 
 	// 解析所有flags
 	flag.Parse()
 
-	if *verbose {
-		log.SetDebug()
-	}
-
 	if *version {
 		if len(gitCommit) > 8 {
 			gitCommit = gitCommit[:8]
@@ -48,6 +51,71 @@ func Options(gitBranch string, gitCommit string) {
 		os.Exit(0)
 	}
 
+	// 读取设置文件，并据此配置日志和数据库
+	s, err := settings.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load settings: %v\n", err)
+		os.Exit(1)
+	}
+	// db migrate 自行应用迁移，以便 --status 能显示尚未应用的迁移
+	migrate := !(flag.NArg() >= 2 && flag.Arg(0) == "db" && flag.Arg(1) == "migrate")
+	if err := setup(s, migrate); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *verbose {
+		log.SetDebug()
+	}
+
+	// 输出格式：--output 优先于设置文件
+	format := *output
+	if format == "" {
+		format = s.Output.Format
+	}
+	if format != cmd.OutputText && !structuredCommands[commandName(flag.Args())] {
+		// 只有文本输出的命令：显式指定 --output 时报错，设置文件中的默认格式不影响这些命令
+		if *output != "" {
+			fmt.Fprintf(os.Stderr, "%s only supports --output text\n", commandName(flag.Args()))
+			os.Exit(1)
+		}
+		format = cmd.OutputText
+	}
+	if err := cmd.SetOutputFormat(format); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	// 未通过 flags 指定时，依次使用目录上下文文件（.dem）和设置文件中的默认作用域
+	if cwd, err := os.Getwd(); err == nil {
+		ctx, err := settings.FindContext(cwd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load context file: %v\n", err)
+			os.Exit(1)
+		}
+		if ctx != nil {
+			log.Debug("Using context file %s", ctx.Path)
+			if *project == defaultValue && ctx.Project != "" {
+				*project = ctx.Project
+			}
+			if *env == defaultValue && ctx.Env != "" {
+				*env = ctx.Env
+			}
+			if *module == defaultValue && ctx.Module != "" {
+				*module = ctx.Module
+			}
+		}
+	}
+	if *project == defaultValue {
+		*project = s.Defaults.Project
+	}
+	if *env == defaultValue {
+		*env = s.Defaults.Env
+	}
+	if *module == defaultValue {
+		*module = s.Defaults.Module
+	}
+
 	// 获取flag解析后的剩余参数
 	args := flag.Args()
 	if len(args) < 1 {
@@ -58,27 +126,48 @@ func Options(gitBranch string, gitCommit string) {
 	// Handle commands
 	switch args[0] {
 	case "add", "create":
-		if len(args) < 3 {
-			fmt.Println("Usage: dem add <key> <value>")
+		fs := flag.NewFlagSet("add", flag.ExitOnError)
+		configType := fs.String("type", "", "Value type [string|number|boolean|json|yaml] (default: the existing type, or string)")
+		force := fs.Bool("force", false, "Save a value whose ${...} references cannot be expanded for the schema check")
+		// flags 只能写在 key 之前，值可以以 - 开头（如负数）
+		fs.Parse(args[1:])
+		rest := fs.Args()
+		if len(rest) < 2 {
+			fmt.Println("Usage: dem add [--type string|number|boolean|json|yaml] [--force] <key> <value>")
 			os.Exit(1)
 		}
-		key := args[1]
-		value := strings.Join(args[2:], " ")
-		cmd.HandleAddCommand(*project, *env, *module, key, *alias, value)
+		key := rest[0]
+		value := strings.Join(rest[1:], " ")
+		// 别名取 --alias，兼容旧写法 dem add <key> <value> [alias]
+		configAlias := *alias
+		if configAlias == "" && len(rest) > 2 {
+			configAlias = rest[2]
+		}
+		cmd.HandleAddCommand(*project, *env, *module, key, configAlias, value, *configType, *force, constant.GetOperator(*as))
 	case "get", "retrieve":
+		fs := flag.NewFlagSet("get", flag.ExitOnError)
+		at := fs.String("at", "", "Show the value as it was at a point in time")
+		raw := fs.Bool("raw", false, "Show the stored value without expanding ${...} references")
+		rest := parseCommandFlags(fs, args[1:])
+		if len(rest) < 1 {
+			fmt.Println("Usage: dem get <key> [--at TIME] [--raw]")
+			os.Exit(1)
+		}
+		key := rest[0]
+		cmd.HandleGetCommand(*project, *env, *module, *verbose, key, *at, *raw)
+	case "info":
 		if len(args) < 2 {
-			fmt.Println("Usage: dem get <key>")
+			fmt.Println("Usage: dem info <key>")
 			os.Exit(1)
 		}
-		key := args[1]
-		cmd.HandleGetCommand(*project, *env, *module, *verbose, key)
+		cmd.HandleInfoCommand(*project, *env, *module, args[1])
 	case "delete", "remove":
 		if len(args) < 2 {
 			fmt.Println("Usage: dem delete <key>")
 			os.Exit(1)
 		}
 		key := args[1]
-		cmd.HandleDeleteCommand(*project, *env, *module, *verbose, key)
+		cmd.HandleDeleteCommand(*project, *env, *module, *verbose, key, constant.GetOperator(*as))
 	case "list", "ls":
 		if len(args) > 1 {
 			switch args[1] {
@@ -91,12 +180,267 @@ func Options(gitBranch string, gitCommit string) {
 			case "-m":
 				cmd.HandleListModules(*project, *env)
 				return
-			case "-a":
-				cmd.HandleListCommand(*project, *env, *module, *verbose, true)
-				return
 			}
 		}
-		cmd.HandleListCommand(*project, *env, *module, *verbose, false)
+		fs := flag.NewFlagSet("list", flag.ExitOnError)
+		show := fs.Bool("a", false, "List with project/env/module details")
+		at := fs.String("at", "", "List configurations as they were at a point in time")
+		parseCommandFlags(fs, args[1:])
+		cmd.HandleListCommand(*project, *env, *module, *verbose, *show, *at)
+	case "history":
+		fs := flag.NewFlagSet("history", flag.ExitOnError)
+		limit := fs.Int("limit", 0, "Show at most N versions")
+		since := fs.String("since", "", "Only show versions since a time (e.g. 2006-01-02, 12h, 7d)")
+		rest := parseCommandFlags(fs, args[1:])
+		if len(rest) < 1 {
+			fmt.Println("Usage: dem history <key> [--limit N] [--since TIME]")
+			os.Exit(1)
+		}
+		cmd.HandleHistoryCommand(*project, *env, *module, *verbose, rest[0], *since, *limit)
+	case "rollback":
+		fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+		to := fs.String("to", "", "History entry ID or version time to restore")
+		steps := fs.Int("steps", 1, "Number of versions to go back")
+		yes := fs.Bool("y", false, "Skip confirmation")
+		rest := parseCommandFlags(fs, args[1:])
+		if len(rest) < 1 {
+			fmt.Println("Usage: dem rollback <key> [--to <version>|--steps N]")
+			os.Exit(1)
+		}
+		cmd.HandleRollbackCommand(*project, *env, *module, *verbose, rest[0], *to, *steps, *yes, constant.GetOperator(*as))
+	case "log":
+		fs := flag.NewFlagSet("log", flag.ExitOnError)
+		by := fs.String("by", "", "Only show changes made by this user")
+		since := fs.String("since", "", "Only show changes since a time (e.g. 2006-01-02, 12h, 7d)")
+		until := fs.String("until", "", "Only show changes up to a time")
+		limit := fs.Int("limit", 50, "Show at most N changes (0 for all)")
+		follow := fs.Bool("follow", false, "Keep polling for new changes")
+		fs.BoolVar(follow, "f", false, "Shorthand for --follow")
+		interval := fs.Duration("interval", 2*time.Second, "Polling interval for --follow")
+		parseCommandFlags(fs, args[1:])
+		cmd.HandleLogCommand(cmd.LogFilter{
+			Project:   *project,
+			Env:       *env,
+			Module:    *module,
+			ChangedBy: *by,
+			Since:     *since,
+			Until:     *until,
+			Limit:     *limit,
+		}, *follow, *interval)
+	case "context":
+		if len(args) < 2 {
+			fmt.Println("Usage: dem context <show|set> [-p project] [-e env] [-m module]")
+			os.Exit(1)
+		}
+		switch args[1] {
+		case "show":
+			cmd.HandleContextShowCommand(*project, *env, *module)
+		case "set":
+			fs := flag.NewFlagSet("context set", flag.ExitOnError)
+			ctxProject := fs.String("p", "", "Project name for this directory")
+			ctxEnv := fs.String("e", "", "Environment for this directory")
+			ctxModule := fs.String("m", "", "Module name for this directory")
+			parseCommandFlags(fs, args[2:])
+			if *ctxProject == "" && *ctxEnv == "" && *ctxModule == "" {
+				fmt.Println("Usage: dem context set [-p project] [-e env] [-m module]")
+				os.Exit(1)
+			}
+			cmd.HandleContextSetCommand(*ctxProject, *ctxEnv, *ctxModule)
+		default:
+			fmt.Printf("Unknown context command: %s\n", args[1])
+			os.Exit(1)
+		}
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ExitOnError)
+		format := fs.String("format", "dotenv", "Export format [dotenv|json|yaml]")
+		file := fs.String("o", "", "Output file, - for stdout (default: .env for dotenv, stdout otherwise)")
+		fs.StringVar(file, "file", "", "Output file, - for stdout (default: .env for dotenv, stdout otherwise)")
+		raw := fs.Bool("raw", false, "Export stored values without expanding ${...} references")
+		mapper := addKeyMapperFlags(fs)
+		parseCommandFlags(fs, args[1:])
+		if *file == "" {
+			*file = "-"
+			if *format == "dotenv" {
+				*file = ".env"
+			}
+		}
+		cmd.HandleExportCommand(*project, *env, *module, *format, *file, mapper(), *raw)
+	case "import":
+		fs := flag.NewFlagSet("import", flag.ExitOnError)
+		format := fs.String("format", "auto", "File format [auto|dotenv|json|yaml|properties]")
+		dryRun := fs.Bool("dry-run", false, "Show what would be imported without writing")
+		overwrite := fs.Bool("overwrite", false, "Overwrite keys that already exist")
+		skipExisting := fs.Bool("skip-existing", false, "Keep keys that already exist")
+		rest := parseCommandFlags(fs, args[1:])
+		if len(rest) < 1 || (*overwrite && *skipExisting) {
+			fmt.Println("Usage: dem import <file> [--format auto|dotenv|json|yaml|properties] [--dry-run] [--overwrite|--skip-existing]")
+			os.Exit(1)
+		}
+		conflict := cmd.ConflictFail
+		if *overwrite {
+			conflict = cmd.ConflictOverwrite
+		} else if *skipExisting {
+			conflict = cmd.ConflictSkip
+		}
+		cmd.HandleImportCommand(*project, *env, *module, rest[0], *format, conflict, *dryRun, constant.GetOperator(*as))
+	case "env":
+		fs := flag.NewFlagSet("env", flag.ExitOnError)
+		shell := fs.String("shell", "", "Shell syntax [bash|zsh|fish|powershell] (default: from $SHELL)")
+		unset := fs.Bool("unset", false, "Print statements that unset the scope's variables")
+		raw := fs.Bool("raw", false, "Use stored values without expanding ${...} references")
+		mapper := addKeyMapperFlags(fs)
+		parseCommandFlags(fs, args[1:])
+		cmd.HandleEnvCommand(*project, *env, *module, *shell, *unset, *raw, mapper())
+	case "render":
+		fs := flag.NewFlagSet("render", flag.ExitOnError)
+		input := fs.String("i", "", "Template file, - for stdin")
+		fs.StringVar(input, "input", "", "Template file, - for stdin")
+		outFile := fs.String("o", "-", "Output file, - for stdout")
+		fs.StringVar(outFile, "out-file", "-", "Output file, - for stdout")
+		raw := fs.Bool("raw", false, "Render stored values without expanding ${...} references")
+		rest := parseCommandFlags(fs, args[1:])
+		if *input == "" && len(rest) > 0 {
+			*input = rest[0]
+		}
+		if *input == "" {
+			fmt.Println("Usage: dem render -i <template> [-o <file>] [--raw]")
+			os.Exit(1)
+		}
+		cmd.HandleRenderCommand(*project, *env, *module, *input, *outFile, *raw)
+	case "hook":
+		fs := flag.NewFlagSet("hook", flag.ExitOnError)
+		raw := fs.Bool("raw", false, "Load stored values without expanding ${...} references")
+		rest := parseCommandFlags(fs, args[1:])
+		if len(rest) >= 2 && rest[0] == "export" {
+			shell, err := cmd.ResolveShell(rest[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "dem: %v\n", err)
+				os.Exit(1)
+			}
+			cmd.HandleHookExportCommand(shell, *project, *env, *module, s.Hook.AllowFile, *raw)
+			return
+		}
+		if len(rest) < 1 {
+			fmt.Println("Usage: dem hook <bash|zsh|fish> [--raw]")
+			os.Exit(1)
+		}
+		cmd.HandleHookCommand(rest[0], hookInvocation(*configPath), *raw)
+	case "allow", "deny":
+		dir := "."
+		if len(args) > 1 {
+			dir = args[1]
+		}
+		cmd.HandleAllowCommand(dir, s.Hook.AllowFile, args[0] == "allow")
+	case "run":
+		fs := flag.NewFlagSet("run", flag.ExitOnError)
+		only := listFlag{}
+		fs.Var(&only, "only", "Only inject keys matching a pattern, e.g. db.* (repeatable or comma-separated)")
+		raw := fs.Bool("raw", false, "Inject stored values without expanding ${...} references")
+		mapper := addKeyMapperFlags(fs)
+		// 遇到第一个位置参数即停止解析，其后的参数原样交给子进程
+		fs.Parse(args[1:])
+		if fs.NArg() < 1 {
+			fmt.Println("Usage: dem run [--only PATTERN] [--prefix P] [--case upper|lower|keep] [--map key=NAME] -- <command> [args...]")
+			os.Exit(1)
+		}
+		cmd.HandleRunCommand(*project, *env, *module, only, mapper(), *raw, fs.Args())
+	case "schema":
+		if len(args) < 2 {
+			fmt.Println("Usage: dem schema <apply FILE|show> [-p project] [-m module]")
+			os.Exit(1)
+		}
+		switch args[1] {
+		case "apply":
+			if len(args) < 3 {
+				fmt.Println("Usage: dem schema apply <file>")
+				os.Exit(1)
+			}
+			cmd.HandleSchemaApplyCommand(*project, *module, args[2], constant.GetOperator(*as))
+		case "show":
+			cmd.HandleSchemaShowCommand(*project, *module)
+		default:
+			fmt.Printf("Unknown schema command: %s\n", args[1])
+			os.Exit(1)
+		}
+	case "validate":
+		cmd.HandleValidateCommand(*project, *env, *module)
+	case "diff":
+		fs := flag.NewFlagSet("diff", flag.ExitOnError)
+		reveal := fs.Bool("reveal", false, "Show encrypted values instead of masking them")
+		rest := parseCommandFlags(fs, args[1:])
+		if len(rest) != 2 {
+			fmt.Println("Usage: dem diff [--reveal] <env|env:module|project:env:module> <env|env:module|project:env:module>")
+			os.Exit(1)
+		}
+		cmd.HandleDiffCommand(*project, *module, rest[0], rest[1], *reveal)
+	case "promote":
+		fs := flag.NewFlagSet("promote", flag.ExitOnError)
+		from := fs.String("from", "", "Source env (env, env:module or project:env:module)")
+		to := fs.String("to", "", "Target env (env, env:module or project:env:module)")
+		keys := listFlag{}
+		fs.Var(&keys, "keys", "Only promote keys matching a pattern, e.g. db.* (repeatable or comma-separated)")
+		onlyMissing := fs.Bool("only-missing", false, "Only add keys missing in the target, keep differing values")
+		reveal := fs.Bool("reveal", false, "Show encrypted values in the preview")
+		yes := fs.Bool("y", false, "Skip confirmation")
+		parseCommandFlags(fs, args[1:])
+		if *from == "" || *to == "" {
+			fmt.Println("Usage: dem promote --from <env> --to <env> [--keys PATTERN] [--only-missing] [--reveal] [-y]")
+			os.Exit(1)
+		}
+		cmd.HandlePromoteCommand(*project, *module, *from, *to, keys, s.Promote.Ignore, *onlyMissing, *reveal, *yes, constant.GetOperator(*as))
+	case "plan", "apply":
+		fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+		file := fs.String("f", "", "Desired state file (YAML or JSON)")
+		fs.StringVar(file, "file", "", "Desired state file (YAML or JSON)")
+		prune := fs.Bool("prune", false, "Delete keys in the scope that are not in the file")
+		reveal := fs.Bool("reveal", false, "Show encrypted values in the plan")
+		autoApprove := false
+		if args[0] == "apply" {
+			fs.BoolVar(&autoApprove, "auto-approve", false, "Apply without asking for confirmation")
+		}
+		rest := parseCommandFlags(fs, args[1:])
+		if *file == "" && len(rest) > 0 {
+			*file = rest[0]
+		}
+		if *file == "" {
+			fmt.Printf("Usage: dem %s -f <desired.yaml> [--prune] [--reveal]\n", args[0])
+			os.Exit(1)
+		}
+		if args[0] == "plan" {
+			cmd.HandlePlanCommand(*project, *env, *module, *file, *prune, *reveal)
+		} else {
+			cmd.HandleApplyCommand(*project, *env, *module, *file, *prune, *reveal, autoApprove, constant.GetOperator(*as))
+		}
+	case "key":
+		if len(args) < 2 {
+			fmt.Println("Usage: dem key <rotate|status>")
+			os.Exit(1)
+		}
+		switch args[1] {
+		case "rotate":
+			fs := flag.NewFlagSet("key rotate", flag.ExitOnError)
+			resume := fs.Bool("resume", false, "Finish an interrupted rotation without generating a new key")
+			parseCommandFlags(fs, args[2:])
+			cmd.HandleKeyRotateCommand(*resume)
+		case "status":
+			cmd.HandleKeyStatusCommand()
+		default:
+			fmt.Printf("Unknown key command: %s\n", args[1])
+			os.Exit(1)
+		}
+	case "db":
+		if len(args) < 2 || args[1] != "migrate" {
+			fmt.Println("Usage: dem db migrate [--status]")
+			os.Exit(1)
+		}
+		fs := flag.NewFlagSet("db migrate", flag.ExitOnError)
+		status := fs.Bool("status", false, "Show applied and pending migrations")
+		parseCommandFlags(fs, args[2:])
+		migrations, err := loadMigrations()
+		if err != nil {
+			log.Fatalf("Failed to load migrations: %v", err)
+		}
+		cmd.HandleMigrateCommand(migrations, *status)
 	default:
 		fmt.Printf("Unknown command: %s\n", args[0])
 		printHelp()
@@ -104,6 +448,147 @@ func Options(gitBranch string, gitCommit string) {
 	}
 }
 
+// structuredCommands 支持 --output json|yaml|table|csv 的命令，键为 commandName 的结果
+var structuredCommands = map[string]bool{
+	"get": true, "retrieve": true, "info": true, "list": true, "ls": true, "history": true, "log": true,
+	"context show": true, "key status": true, "db migrate": true,
+	"schema show": true, "validate": true, "plan": true, "apply": true, "diff": true,
+}
+
+// commandName 返回命令名，带子命令的命令（如 key status）包含子命令
+func commandName(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	switch args[0] {
+	case "context", "key", "db", "schema":
+		if len(args) > 1 {
+			return args[0] + " " + args[1]
+		}
+	}
+	return args[0]
+}
+
+// setup 按设置配置日志、主密钥文件并打开数据库；migrate 为 true 时迁移数据库，并加密早期版本遗留的明文值
+func setup(s *settings.Settings, migrate bool) error {
+	if err := log.Configure(false, s.Log.Path); err != nil {
+		return fmt.Errorf("failed to configure logger: %w", err)
+	}
+	if err := log.SetLevel(s.Log.Level); err != nil {
+		return err
+	}
+	for _, path := range []string{s.Database.Path, s.Database.KeyFile} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+	}
+	secret.SetKeyFilePath(s.Database.KeyFile)
+	if err := db.InitDB(s.Database.Path); err != nil {
+		return err
+	}
+	if !migrate {
+		return nil
+	}
+	Init()
+	counts, err := db.EncryptLegacy()
+	if err != nil {
+		return fmt.Errorf("failed to encrypt plaintext values: %w", err)
+	}
+	for table, n := range counts {
+		if n > 0 {
+			log.Info("Encrypted %d plaintext values in %s", n, table)
+		}
+	}
+	return nil
+}
+
+// mapFlag 可重复的 key=value 参数，如 --map database.host=DB_HOST
+type mapFlag map[string]string
+
+func (m mapFlag) String() string {
+	var pairs []string
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m mapFlag) Set(value string) error {
+	key, name, ok := strings.Cut(value, "=")
+	if !ok || key == "" || name == "" {
+		return fmt.Errorf("expected key=NAME, got %q", value)
+	}
+	m[key] = name
+	return nil
+}
+
+// hookInvocation 返回钩子脚本中调用 dem 的命令，保留 -c 指定的设置文件
+func hookInvocation(configPath string) []string {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = os.Args[0]
+	}
+	invocation := []string{executable}
+	if configPath != "" {
+		if abs, err := filepath.Abs(configPath); err == nil {
+			configPath = abs
+		}
+		invocation = append(invocation, "-c", configPath)
+	}
+	return invocation
+}
+
+// listFlag 可重复且支持逗号分隔的参数，如 --only db.* --only app.url,app.port
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// addKeyMapperFlags 为子命令注册配置键到环境变量名的映射参数，解析后调用返回的函数获取映射规则
+// 规则无效时输出错误并退出
+func addKeyMapperFlags(fs *flag.FlagSet) func() cmd.KeyMapper {
+	prefix := fs.String("prefix", "", "Prefix added to every variable name")
+	keyCase := fs.String("case", "upper", "Case of variable names [upper|lower|keep]")
+	overrides := mapFlag{}
+	fs.Var(overrides, "map", "Explicit variable name for a key, e.g. database.host=DB_HOST (repeatable)")
+	return func() cmd.KeyMapper {
+		mapper := cmd.KeyMapper{Prefix: *prefix, Case: *keyCase, Overrides: overrides}
+		if err := mapper.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return mapper
+	}
+}
+
+// parseCommandFlags 解析子命令的 flags，允许 flags 与位置参数交替出现，返回位置参数
+func parseCommandFlags(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		// "--" 之后的参数全部视为位置参数
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...)
+		}
+		if len(rest) == 0 {
+			return positional
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
 // 美化版本信息输出（新增函数）
 func printVersionInfo(branch, commit string) {
 	fmt.Print(constant.VersionHeader)
@@ -130,15 +615,131 @@ Options:
   -m, --module TEXT             Specify module name (default: default)
   -v, --verbose                 Enable verbose output
   --alias TEXT                  Specify custom alias for the config
-  -c, --config TEXT             Specify config file path
+  --as TEXT                     Record changes as this user (default: $DEM_USER or the OS user)
+  -c, --config TEXT             Specify settings file path (default: ~/.dem/config.toml)
+  --output [text|json|yaml|table|csv]
+                                Output format for list, get, info, history, log, schema show,
+                                validate, diff, plan, apply, context show, key status and
+                                db migrate (default: text); other commands only print text
+                                and reject an explicit non-text --output
   --version                     Show version and build information
 
 Commands:
-  add, create                   Add key-value configuration (Usage: dem add <key> <value>)
-  get, retrieve                Get key-value configuration (Usage: dem get <key>)
+  add, create                   Add key-value configuration (Usage: dem add [--type T] <key> <value>)
+                               T is string, number, boolean, json or yaml; values are validated
+  get, retrieve                Get key-value configuration (Usage: dem get <key> [--at TIME] [--raw])
   delete, remove               Delete key-value configuration
-  list, ls                     List all configurations
-  info                         Show configuration details
+  list, ls                     List all configurations (--at TIME for a past snapshot)
+  info <key>                   Show all columns of the resolved key, its history count and
+                               the fallback layers it shadows
+  history <key>                Show previous versions of a key (--limit N, --since TIME)
+  log                          Show recent changes across all projects, newest first
+                               (--by USER, --since TIME, --until TIME, --limit N, -f/--follow)
+  rollback <key>               Restore a previous version, also for deleted keys
+                               (--to <id|version>, --steps N, -y to skip confirmation)
+  export                       Export a scope to a file (--format dotenv|json|yaml, -o FILE|-,
+                               --prefix P, --case upper|lower|keep, --map key=NAME)
+  import <file>                Load keys from a dotenv, JSON, YAML or properties file in one
+                               transaction (--format, --dry-run, --overwrite|--skip-existing)
+  env                          Print shell statements that set the scope's keys, for eval
+                               (--shell bash|zsh|fish|powershell, --unset, --prefix, --case, --map)
+  render -i TMPL [-o FILE]     Render a Go text/template with the scope's keys; fails listing
+                               every missing key ({{ dem "key" ["env" ["module"]] }}, optional,
+                               default, required, toJson, fromJson, quote, indent, ...; --raw)
+  hook <bash|zsh|fish>         Print a shell hook that loads the .dem context of the current
+                               directory on every prompt and unloads it when you leave (--raw)
+  allow [dir], deny [dir]      Allow or revoke automatic loading of a .dem file by the hook
+  run -- <command>             Run a command with the scope's keys added to its environment
+                               (--only PATTERN, --prefix P, --case, --map key=NAME)
+  schema apply <file>          Replace the project/module schema: required keys per env, types,
+                               enum, pattern, min/max and descriptions; add and import reject
+                               values that break it or whose references cannot be expanded
+                               (add --force saves such a reference unchecked)
+  schema show                  Show the schema of the project/module
+  validate                     Check every env (or -e) against the schema; reports missing,
+                               invalid and extra keys and exits 1 on missing or invalid ones
+  diff A B                     Compare the keys stored in two scopes; A and B are env, env:module
+                               or project:env:module. Encrypted values are masked unless
+                               --reveal; exits 1 when the scopes differ
+  promote --from E1 --to E2     Copy keys from one env to another after a preview and confirmation
+                               (--keys PATTERN, --only-missing, --reveal, -y); keys listed in
+                               [promote.ignore] for the project are never copied
+  plan -f FILE                 Show the adds, changes and deletes needed to make a scope match
+                               the desired state in FILE (--prune to delete keys not in FILE);
+                               encrypted values are masked unless --reveal
+  apply -f FILE                Apply that plan in one transaction after confirmation
+                               (--prune, --reveal, --auto-approve)
+  context show                 Show the .dem context file in effect and the resolved scope
+  context set                  Write project/env/module defaults to ./.dem (-p, -e, -m)
+  key rotate [--resume]        Re-encrypt all values and history under a new master key
+  key status                   Show master keys and how many rows use each
+  db migrate [--status]        Apply pending schema migrations or show their status
+
+Settings file (~/.dem/config.toml, or the file given with -c):
+  [database]
+  path = "~/.dem/dem_config.db"   # database location
+  key_file = "~/.dem/master.key"  # master key, defaults to the database directory
+  [defaults]
+  project = "default"             # used when -p/-e/-m are not given
+  env = "default"
+  module = "default"
+  [log]
+  path = "~/.dem/dem.log"
+  level = "info"                  # debug | info | warning | error
+  [output]
+  format = "text"                 # text | json | yaml | table | csv, overridden by --output
+  [hook]
+  allow_file = "~/.dem/allow"     # .dem files the shell hook may load
+  [promote.ignore]
+  myproject = ["db.*", "*.url"]   # keys that differ per env and are never promoted
+
+Output schemas (--output json|yaml; table and csv show the listed columns):
+  Config fields follow the json tags of models.ConfigMaster; null fields are omitted:
+    id, project, env, module, config_key, auto_alias, config_alias, config_value, config_type,
+    description, is_encrypted, sort_order, created_time, updated_time, updated_by
+  list               array of config objects
+                     columns: id project env module config_key config_value config_type
+                              config_alias auto_alias updated_time
+  list -p|-e|-m      array of names; column: project, env or module
+  get                config object (value with references expanded unless --raw) plus
+                     layer, layers, matched_by, wildcard; exits 1 if not found or ambiguous
+                     columns: project env module config_key config_value config_type layer matched_by
+  info               get object plus resolved_value, history_versions, shadows (array)
+                     columns: id project env module config_key config_value resolved_value
+                              config_type is_encrypted created_time updated_time
+                              history_versions layer shadows
+  history            array of config objects plus version, changed_by, change_type
+                     columns: id version changed_by change_type project env module config_key
+                              config_type config_value
+  log                array of {id, version, changed_by, change_type, project, env, module,
+                     config_key}; with -f one JSON object per line (json only)
+  schema show        array of {id, project, module, config_key, config_type, required, envs,
+                     enum, pattern, min, max, description}
+                     columns: config_key config_type required envs enum pattern min max description
+  diff               {left, right, identical, differences: [{config_key, status, left_value,
+                     right_value, left_type, right_type, value_differs, type_differs}]};
+                     status is only_left, only_right or changed
+                     columns: config_key status left_value right_value left_type right_type
+  plan               {project, env, module, changes: [{action, config_key, old_value, new_value,
+                     old_type, new_type}], unmanaged: [keys]}; action is add, change or delete
+                     columns: action config_key old_value new_value old_type new_type
+  apply              same as plan, listing the applied changes; requires --auto-approve
+  validate           {project, module, envs, keys, valid, issues: [{env, config_key, problem,
+                     message, source}]}; problem is missing, invalid or extra
+                     columns: env config_key problem message source
+  context show       {context_file, project, env, module}
+  key status         {key_file, keys: [{id, created, passphrase, rows, status}], pending: {key: rows}}
+  db migrate         array of {version, name, status, applied_time}, after applying
+                     pending migrations unless --status
+  Times are RFC 3339.
+
+Environment:
+  DEM_MASTER_PASSPHRASE        Passphrase for the master key (~/.dem/master.key).
+                               Values are encrypted at rest with AES-256-GCM; when unset,
+                               a random passphrase is generated and kept in the key file.
+  DEM_NEW_MASTER_PASSPHRASE    Passphrase for the new key created by 'dem key rotate'
+  DEM_HOOK_STATE               Set by the shell hook to track the variables it loaded
+  DEM_USER                     Identity recorded as changed_by in the history
 
 Examples:
   
@@ -156,6 +757,43 @@ Examples:
   dem -p myproject -e dev -m database get database.host
   dem -p myproject -e dev -m database delete database.host
   
+  # Typed values: rejected when they do not parse, exported with their type
+  dem add --type boolean app.debug true
+  dem add --type number app.port 8080
+  dem add --type json app.features '["search", "export"]'
+  dem -e dev export --format json -o config.json   # {"app": {"debug": true, "port": 8080, ...}}
+
+  # Declare what a module needs, then check every env against it
+  #   schema.yaml:  project: app
+  #                 module: api
+  #                 envs: [dev, prod]
+  #                 keys:
+  #                   db.port: {type: number, min: 1, max: 65535, description: Database port}
+  #                   log.level: {enum: [debug, info, warn, error], required: false}
+  dem schema apply schema.yaml
+  dem -p app -e prod -m api validate          # missing, invalid and extra keys in prod
+  dem -p app -e prod -m api add db.port 70000 # rejected: above the maximum 65535
+
+  # Compare environments before a release (exit code 1 when they differ)
+  dem -p app diff dev prod                    # - only in dev, + only in prod, ~ different
+  dem diff app:dev:api app:prod:api --reveal  # show the encrypted values as well
+  dem -p app diff test prod || echo "prod is missing keys"
+
+  # Promote a tested configuration to prod
+  dem -p app promote --from test --to prod                  # preview, then confirm
+  dem -p app promote --from test --to prod --only-missing   # only add keys prod lacks
+  dem -p app promote --from test --to prod --keys 'feature.*' -y
+
+  # Review configuration as code, then apply it
+  #   prod.yaml:  project: app
+  #               env: prod
+  #               keys:
+  #                 db: {host: db1.internal, port: 5432}
+  #                 log.level: warn
+  dem plan -f prod.yaml                       # + add, ~ change, - delete
+  dem apply -f prod.yaml --prune              # also delete keys that are not in the file
+  dem apply -f prod.yaml --auto-approve       # in CI after the pull request is merged
+
   # Adding complex configuration values (including spaces)
   dem add app.description "My Application Description"
   dem add app.features "feature1, feature2, feature3"
@@ -166,7 +804,36 @@ Examples:
   dem list -p                        # List all projects
   dem list -e                        # List all environments for current project
   dem list -m                        # List all modules for current project and environment
+  dem -e prod list --at "2026-01-02 15:04"   # List prod as it stood at that time
+
+  # Layered lookup: (app,prod,api) -> (app,prod,default) -> (app,default,default) -> (default,default,default)
+  dem -p app add db.port 3306                  # shared by every env and module of app
+  dem -p app -e prod add db.port 3307          # prod override
+  dem -v -p app -e prod -m api get db.port     # 3307, source shown on stderr
+
+  # References between keys, expanded by get, export, env, run, render and hook (--raw to disable)
+  dem -e dev add db.host db1.internal
+  dem -e dev add db.url 'jdbc:mysql://${db.host}:3306/app'     # same project/env/module
+  dem -e dev add replica.host '${prod:database:db.host}'       # ${env:key} or ${env:module:key}
+  dem -e dev get db.url --raw                                  # jdbc:mysql://${db.host}:3306/app
+
+  # Point-in-time reads
+  dem -e prod get app.url --at "2026-01-02 15:04:05"
+  dem -e prod get app.url --at 2h          # Value two hours ago
   
+  # History of changes
+  dem -e prod history app.url
+  dem -e prod history app.url --since 7d --limit 5
+  dem -e prod rollback app.url                 # undo the last change
+  dem -e prod rollback app.url --to 42         # restore history entry #42
+  dem -e prod log --since 7d                   # what changed in prod this week
+  dem log -f                                   # follow changes as they happen
+
+  # Structured output for scripts
+  dem -p myapp -e prod --output json list | jq -r '.[] | "\(.config_key)=\(.config_value)"'
+  dem -p myapp -e prod --output json get db.host | jq -r .config_value
+  dem -p myapp --output csv history db.host > db-host-history.csv
+
   # Verbose output
   dem -v add app.debug true
   dem -v get app.debug
@@ -177,6 +844,42 @@ Examples:
   dem -e dev get app.url
   dem -e prod get app.url
   
+  # Export a scope as a dotenv file
+  dem -p myapp -e dev export                      # writes .env, database.host -> DATABASE_HOST
+  dem -p myapp -e dev export -o - --prefix APP_ --map db.url=DATABASE_URL
+
+  # Load a scope into the current shell, then switch from dev to prod
+  eval "$(dem -p myapp -e dev env)"
+  eval "$(dem -p myapp -e dev env --unset)"; eval "$(dem -p myapp -e prod env)"
+  dem -p myapp -e dev env --shell fish | source
+  dem -p myapp -e dev env --shell powershell | Invoke-Expression
+
+  # Render config files from templates
+  dem -p myapp -e prod render -i nginx.conf.tmpl -o nginx.conf
+  #   server_name {{ dem "app.host" }};  proxy_pass http://{{ dem "api.host" "prod" "api" }};
+  #   port: {{ optional "server.port" | default "8080" }}
+
+  # Load the directory's scope automatically on cd (add to ~/.bashrc, ~/.zshrc or config.fish)
+  eval "$(dem hook bash)"
+  eval "$(dem hook zsh)"
+  dem hook fish | source
+  cd ~/src/myproject && dem allow   # trust this .dem file; re-run after it changes
+
+  # Run a process with a scope injected into its environment
+  dem -p myapp -e dev run -- npm start
+  dem -p myapp -e dev run --only 'db.*' --prefix APP_ -- ./server --port 8080
+
+  # Import an existing configuration file
+  dem -p myapp -e dev import application-dev.yml --dry-run
+  dem -p myapp -e dev import .env --overwrite
+
+  # Per-directory context: flags > nearest .dem file > settings > default
+  cd ~/src/myproject && dem context set -p myproject -e dev -m api
+  dem get db.host                    # same as dem -p myproject -e dev -m api get db.host
+
+  # Separate stores per client
+  dem -c ~/clients/acme/dem.toml list
+
   # Working with different modules
   dem -m redis add redis.host redis-server
   dem -m database add db.host postgresql-server
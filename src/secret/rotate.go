@@ -0,0 +1,108 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+)
+
+// HasKey 判断主密钥文件是否存在，密钥文件在第一次加密时创建
+func HasKey() (bool, error) {
+	_, err := os.Stat(GetKeyFilePath())
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Keys 返回密钥文件中的全部密钥及当前使用的密钥 ID，密钥文件不存在时创建
+func Keys() (*KeyRing, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r, err := loadRing(true)
+	if err != nil {
+		return nil, err
+	}
+	copied := *r
+	copied.Keys = append([]MasterKey{}, r.Keys...)
+	return &copied, nil
+}
+
+// Rotate 生成新的主密钥并设为当前密钥，旧密钥保留在密钥文件中直到数据重新加密完成
+// 新密钥的口令取自 DEM_NEW_MASTER_PASSPHRASE，未设置时随机生成
+func Rotate() (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r, err := loadRing(true)
+	if err != nil {
+		return "", err
+	}
+	mk, err := newMasterKey(os.Getenv(NewPassphraseEnv))
+	if err != nil {
+		return "", err
+	}
+	updated := &KeyRing{Active: mk.ID, Keys: append(append([]MasterKey{}, r.Keys...), mk)}
+	if err := saveRing(updated); err != nil {
+		return "", err
+	}
+	ring = updated
+	return mk.ID, nil
+}
+
+// Reencrypt 将加密值（或历史明文值）改用当前密钥加密
+// 已使用当前密钥的值原样返回，changed 为 false
+func Reencrypt(value string) (sealed string, changed bool, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r, err := loadRing(true)
+	if err != nil {
+		return "", false, err
+	}
+	if KeyID(value) == r.Active {
+		return value, false, nil
+	}
+	plain := value
+	if IsEnvelope(value) {
+		if plain, err = decrypt(value); err != nil {
+			return "", false, err
+		}
+	}
+	sealed, err = encryptWith(r.Active, plain)
+	if err != nil {
+		return "", false, err
+	}
+	return sealed, true, nil
+}
+
+// Prune 从密钥文件中移除不再被任何数据引用的旧密钥，返回被移除的密钥 ID
+func Prune(inUse map[string]int) ([]string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r, err := loadRing(true)
+	if err != nil {
+		return nil, err
+	}
+	updated := &KeyRing{Active: r.Active}
+	var removed []string
+	for _, mk := range r.Keys {
+		if mk.ID != r.Active && inUse[mk.ID] == 0 {
+			removed = append(removed, mk.ID)
+			continue
+		}
+		updated.Keys = append(updated.Keys, mk)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	if err := saveRing(updated); err != nil {
+		return nil, fmt.Errorf("failed to save key file: %w", err)
+	}
+	ring = updated
+	for _, id := range removed {
+		delete(derived, id)
+	}
+	return removed, nil
+}
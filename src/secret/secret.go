@@ -0,0 +1,332 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+)
+
+// 加密值的存储格式：enc:v1:<key_id>:<base64(nonce|ciphertext)>
+const (
+	envelopePrefix = "enc:v1:"
+
+	// PassphraseEnv 指定主密钥口令的环境变量，设置后口令不会写入密钥文件
+	PassphraseEnv = "DEM_MASTER_PASSPHRASE"
+	// NewPassphraseEnv 密钥轮换时新主密钥使用的口令
+	NewPassphraseEnv = "DEM_NEW_MASTER_PASSPHRASE"
+
+	checkPlaintext = "dem-master-key"
+
+	kdfIterations = 210000
+	keyLength     = 32
+	saltLength    = 16
+)
+
+// MasterKey 密钥文件中的一条主密钥记录
+type MasterKey struct {
+	ID         string    `json:"id"`
+	Salt       string    `json:"salt"`
+	Passphrase string    `json:"passphrase,omitempty"` // 为空时从 DEM_MASTER_PASSPHRASE 读取
+	Check      string    `json:"check,omitempty"`      // 用于校验口令是否正确的密文
+	Created    time.Time `json:"created"`
+}
+
+// KeyRing 密钥文件内容：当前使用的密钥 ID 及全部密钥
+type KeyRing struct {
+	Active string      `json:"active"`
+	Keys   []MasterKey `json:"keys"`
+}
+
+var (
+	mu      sync.Mutex
+	ring    *KeyRing
+	derived = map[string][]byte{}
+	keyFile string
+)
+
+// GetKeyFilePath 返回主密钥文件路径，默认为 ~/.dem/master.key
+func GetKeyFilePath() string {
+	if keyFile != "" {
+		return keyFile
+	}
+	return filepath.Join(constant.GetProjectDir(), constant.KeyFileName)
+}
+
+// SetKeyFilePath 指定主密钥文件路径（来自设置文件）
+func SetKeyFilePath(path string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	keyFile = path
+	ring = nil
+	derived = map[string][]byte{}
+}
+
+// IsEnvelope 判断值是否为加密后的存储格式
+func IsEnvelope(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// Encrypt 使用当前主密钥加密明文，返回可直接写入数据库的字符串
+func Encrypt(plain string) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r, err := loadRing(true)
+	if err != nil {
+		return "", err
+	}
+	return encryptWith(r.Active, plain)
+}
+
+// Decrypt 解密数据库中的值；非加密格式的值返回错误，历史明文数据在启动时由 db.EncryptLegacy 加密
+func Decrypt(value string) (string, error) {
+	if !IsEnvelope(value) {
+		return "", errors.New("value is marked as encrypted but is not in encrypted format")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	return decrypt(value)
+}
+
+// KeyID 返回加密值所使用的密钥 ID，明文值返回空字符串
+func KeyID(value string) string {
+	if !IsEnvelope(value) {
+		return ""
+	}
+	keyID, _, _ := strings.Cut(strings.TrimPrefix(value, envelopePrefix), ":")
+	return keyID
+}
+
+func decrypt(value string) (string, error) {
+	keyID, payload, ok := strings.Cut(strings.TrimPrefix(value, envelopePrefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+	aead, err := aeadFor(keyID)
+	if err != nil {
+		return "", err
+	}
+	plain, err := open(aead, payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value with key %s (wrong passphrase?)", keyID)
+	}
+	return plain, nil
+}
+
+func open(aead cipher.AEAD, payload string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func seal(aead cipher.AEAD, plain string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func encryptWith(keyID, plain string) (string, error) {
+	aead, err := aeadFor(keyID)
+	if err != nil {
+		return "", err
+	}
+	payload, err := seal(aead, plain)
+	if err != nil {
+		return "", err
+	}
+	return envelopePrefix + keyID + ":" + payload, nil
+}
+
+// aeadFor 根据密钥 ID 派生 AES-256-GCM 实例，派生结果在进程内缓存
+func aeadFor(keyID string) (cipher.AEAD, error) {
+	key, ok := derived[keyID]
+	if !ok {
+		r, err := loadRing(false)
+		if err != nil {
+			return nil, err
+		}
+		mk := r.find(keyID)
+		if mk == nil {
+			return nil, fmt.Errorf("master key %s not found in %s", keyID, GetKeyFilePath())
+		}
+		key, err = deriveKey(mk)
+		if err != nil {
+			return nil, err
+		}
+		derived[keyID] = key
+	}
+	return newAEAD(key)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// deriveKey 依次尝试密钥文件中的口令、DEM_NEW_MASTER_PASSPHRASE 和 DEM_MASTER_PASSPHRASE，
+// 以 check 字段校验派生结果
+func deriveKey(mk *MasterKey) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(mk.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt for key %s: %w", mk.ID, err)
+	}
+	var candidates []string
+	if mk.Passphrase != "" {
+		candidates = append(candidates, mk.Passphrase)
+	} else {
+		for _, name := range []string{NewPassphraseEnv, PassphraseEnv} {
+			if passphrase := os.Getenv(name); passphrase != "" {
+				candidates = append(candidates, passphrase)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("key %s requires a passphrase, set %s", mk.ID, PassphraseEnv)
+		}
+	}
+	for _, passphrase := range candidates {
+		key, err := pbkdf2.Key(sha256.New, passphrase, salt, kdfIterations, keyLength)
+		if err != nil {
+			return nil, err
+		}
+		if mk.Check == "" {
+			return key, nil
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		if plain, err := open(aead, mk.Check); err == nil && plain == checkPlaintext {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("wrong passphrase for key %s", mk.ID)
+}
+
+func (r *KeyRing) find(keyID string) *MasterKey {
+	for i := range r.Keys {
+		if r.Keys[i].ID == keyID {
+			return &r.Keys[i]
+		}
+	}
+	return nil
+}
+
+// loadRing 读取密钥文件，create 为 true 且文件不存在时生成新的主密钥
+func loadRing(create bool) (*KeyRing, error) {
+	if ring != nil {
+		return ring, nil
+	}
+	path := GetKeyFilePath()
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if !create {
+			return nil, fmt.Errorf("master key file %s does not exist", path)
+		}
+		mk, err := newMasterKey(os.Getenv(PassphraseEnv))
+		if err != nil {
+			return nil, err
+		}
+		r := &KeyRing{Active: mk.ID, Keys: []MasterKey{mk}}
+		if err := saveRing(r); err != nil {
+			return nil, err
+		}
+		ring = r
+		return ring, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var r KeyRing
+	if err := json.Unmarshal(content, &r); err != nil {
+		return nil, fmt.Errorf("invalid master key file %s: %w", path, err)
+	}
+	if r.find(r.Active) == nil {
+		return nil, fmt.Errorf("active key %s not found in %s", r.Active, path)
+	}
+	ring = &r
+	return ring, nil
+}
+
+func saveRing(r *KeyRing) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := GetKeyFilePath()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// newMasterKey 生成新的主密钥；passphrase 为空时随机生成口令并保存在密钥文件中
+func newMasterKey(passphrase string) (MasterKey, error) {
+	id := make([]byte, 4)
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(id); err != nil {
+		return MasterKey{}, err
+	}
+	if _, err := rand.Read(salt); err != nil {
+		return MasterKey{}, err
+	}
+	mk := MasterKey{
+		ID:      hex.EncodeToString(id),
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Created: time.Now(),
+	}
+	if passphrase == "" {
+		random := make([]byte, keyLength)
+		if _, err := rand.Read(random); err != nil {
+			return MasterKey{}, err
+		}
+		passphrase = base64.StdEncoding.EncodeToString(random)
+		mk.Passphrase = passphrase
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, kdfIterations, keyLength)
+	if err != nil {
+		return MasterKey{}, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return MasterKey{}, err
+	}
+	if mk.Check, err = seal(aead, checkPlaintext); err != nil {
+		return MasterKey{}, err
+	}
+	derived[mk.ID] = key
+	return mk, nil
+}
@@ -0,0 +1,101 @@
+package secret
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useKeyFile 让测试使用临时目录中的密钥文件和指定口令
+func useKeyFile(t *testing.T, passphrase string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "master.key")
+	t.Setenv(PassphraseEnv, passphrase)
+	t.Setenv(NewPassphraseEnv, "")
+	SetKeyFilePath(path)
+	t.Cleanup(func() { SetKeyFilePath("") })
+	return path
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	useKeyFile(t, "correct horse")
+
+	tests := []string{"", "localhost", "p@ss:word", "多字节 value", strings.Repeat("x", 4096)}
+	for _, plain := range tests {
+		sealed, err := Encrypt(plain)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", plain, err)
+		}
+		if !IsEnvelope(sealed) {
+			t.Errorf("Encrypt(%q) = %q, want prefix %q", plain, sealed, envelopePrefix)
+		}
+		if plain != "" && strings.Contains(sealed, plain) {
+			t.Errorf("Encrypt(%q) = %q contains the plaintext", plain, sealed)
+		}
+		got, err := Decrypt(sealed)
+		if err != nil {
+			t.Fatalf("Decrypt(%q): %v", sealed, err)
+		}
+		if got != plain {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q", plain, got)
+		}
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	useKeyFile(t, "correct horse")
+	sealed, err := Encrypt("value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := strings.SplitN(sealed, ":", 4)[2]
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"plaintext", "value"},
+		{"missing payload", envelopePrefix + keyID},
+		{"bad base64", envelopePrefix + keyID + ":!!!"},
+		{"short payload", envelopePrefix + keyID + ":AAAA"},
+		{"unknown key", envelopePrefix + "00000000:" + strings.SplitN(sealed, ":", 4)[3]},
+		{"tampered", sealed[:len(sealed)-4] + "AAA="},
+	}
+	for _, tt := range tests {
+		if got, err := Decrypt(tt.value); err == nil {
+			t.Errorf("%s: Decrypt(%q) = %q, want error", tt.name, tt.value, got)
+		}
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	path := useKeyFile(t, "correct horse")
+	sealed, err := Encrypt("value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		passphrase string
+		wantErr    string
+	}{
+		{"battery staple", "wrong passphrase"},
+		{"", "requires a passphrase"},
+		{"correct horse", ""},
+	}
+	for _, tt := range tests {
+		// 重新指定密钥文件以清除进程内缓存的派生密钥
+		t.Setenv(PassphraseEnv, tt.passphrase)
+		SetKeyFilePath(path)
+		got, err := Decrypt(sealed)
+		if tt.wantErr == "" {
+			if err != nil || got != "value" {
+				t.Errorf("passphrase %q: Decrypt = %q, %v", tt.passphrase, got, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("passphrase %q: Decrypt error = %v, want %q", tt.passphrase, err, tt.wantErr)
+		}
+	}
+}
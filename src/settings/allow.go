@@ -0,0 +1,107 @@
+package settings
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AllowList 允许 shell 钩子自动加载的上下文文件
+// 每行记录上下文文件的 SHA-256 与绝对路径，文件内容变化后需要重新允许，
+// 防止克隆下来的仓库通过 .dem 文件悄悄加载其他环境（如 prod）的凭据
+//
+//	<sha256>  /home/me/src/myproject/.dem
+type AllowList struct {
+	Path    string
+	entries map[string]string // 上下文文件路径 -> 允许时的 SHA-256
+}
+
+// LoadAllowList 读取允许列表，文件不存在时返回空列表
+func LoadAllowList(path string) (*AllowList, error) {
+	l := &AllowList{Path: path, entries: map[string]string{}}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sum, contextPath, ok := strings.Cut(line, "  ")
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid line %q", path, line)
+		}
+		l.entries[contextPath] = sum
+	}
+	return l, scanner.Err()
+}
+
+// Allowed 判断上下文文件是否已被允许且内容未变化
+func (l *AllowList) Allowed(contextPath string) (bool, error) {
+	sum, ok := l.entries[contextPath]
+	if !ok {
+		return false, nil
+	}
+	current, err := hashFile(contextPath)
+	if err != nil {
+		return false, err
+	}
+	return current == sum, nil
+}
+
+// Allow 按当前内容允许上下文文件
+func (l *AllowList) Allow(contextPath string) error {
+	sum, err := hashFile(contextPath)
+	if err != nil {
+		return err
+	}
+	l.entries[contextPath] = sum
+	return nil
+}
+
+// Deny 从列表中移除上下文文件，返回其原本是否在列表中
+func (l *AllowList) Deny(contextPath string) bool {
+	_, ok := l.entries[contextPath]
+	delete(l.entries, contextPath)
+	return ok
+}
+
+// Save 将允许列表写回 l.Path
+func (l *AllowList) Save() error {
+	paths := make([]string, 0, len(l.entries))
+	for path := range l.entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	b.WriteString("# dem allow list: context files the shell hook may load\n")
+	for _, path := range paths {
+		fmt.Fprintf(&b, "%s  %s\n", l.entries[path], path)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(l.Path, []byte(b.String()), 0600)
+}
+
+func hashFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,92 @@
+package settings
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+)
+
+// Context 目录级的上下文文件（.dem），为该目录及其子目录提供默认的 project/env/module
+//
+//	# dem context
+//	project=myproject
+//	env=dev
+//	module=api
+type Context struct {
+	Path    string
+	Project string
+	Env     string
+	Module  string
+}
+
+// FindContext 从 dir 开始逐级向上查找 .dem 上下文文件，未找到时返回 nil
+// 名为 .dem 的目录（如 ~/.dem 数据目录）会被跳过
+func FindContext(dir string) (*Context, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		path := filepath.Join(dir, constant.ContextFileName)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return LoadContext(path)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// LoadContext 读取指定的上下文文件
+func LoadContext(path string) (*Context, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	c := &Context{Path: path}
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected name=value", path, lineNo)
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.TrimSpace(name) {
+		case "project":
+			c.Project = value
+		case "env":
+			c.Env = value
+		case "module":
+			c.Module = value
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", path, lineNo, strings.TrimSpace(name))
+		}
+	}
+	return c, scanner.Err()
+}
+
+// Save 将上下文写回 c.Path
+func (c *Context) Save() error {
+	var b strings.Builder
+	b.WriteString("# dem context: default project/env/module for this directory\n")
+	for _, field := range [][2]string{{"project", c.Project}, {"env", c.Env}, {"module", c.Module}} {
+		if field[1] != "" {
+			fmt.Fprintf(&b, "%s=%s\n", field[0], field[1])
+		}
+	}
+	return os.WriteFile(c.Path, []byte(b.String()), 0644)
+}
@@ -0,0 +1,205 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/zhangymPerson/dev-env-manage/src/constant"
+)
+
+// Settings 对应 dem 的设置文件（默认 ~/.dem/config.toml）
+//
+//	[database]
+//	path = "~/.dem/dem_config.db"
+//	key_file = "~/.dem/master.key"
+//
+//	[defaults]
+//	project = "myproject"
+//	env = "dev"
+//	module = "default"
+//
+//	[log]
+//	path = "~/.dem/dem.log"
+//	level = "info"        # debug | info | warning | error
+//
+//	[output]
+//	format = "text"       # text | json | yaml | table | csv
+//
+//	[hook]
+//	allow_file = "~/.dem/allow"
+//
+//	[promote.ignore]
+//	myproject = ["db.*", "*.url"]
+type Settings struct {
+	Database DatabaseSettings `toml:"database"`
+	Defaults DefaultSettings  `toml:"defaults"`
+	Log      LogSettings      `toml:"log"`
+	Output   OutputSettings   `toml:"output"`
+	Hook     HookSettings     `toml:"hook"`
+	Promote  PromoteSettings  `toml:"promote"`
+}
+
+// DatabaseSettings 数据库及主密钥文件位置
+type DatabaseSettings struct {
+	Path    string `toml:"path"`
+	KeyFile string `toml:"key_file"`
+}
+
+// DefaultSettings 未通过 -p/-e/-m 指定时使用的默认作用域
+type DefaultSettings struct {
+	Project string `toml:"project"`
+	Env     string `toml:"env"`
+	Module  string `toml:"module"`
+}
+
+// LogSettings 日志文件位置与级别
+type LogSettings struct {
+	Path  string `toml:"path"`
+	Level string `toml:"level"`
+}
+
+// OutputSettings 命令输出格式
+type OutputSettings struct {
+	Format string `toml:"format"`
+}
+
+// HookSettings shell 钩子设置
+type HookSettings struct {
+	AllowFile string `toml:"allow_file"` // 允许钩子自动加载的上下文文件列表
+}
+
+// PromoteSettings dem promote 设置
+type PromoteSettings struct {
+	Ignore map[string][]string `toml:"ignore"` // 按项目列出各环境应当不同、promote 不复制的键（path.Match 模式）
+}
+
+// DefaultPath 返回默认设置文件路径
+func DefaultPath() string {
+	return filepath.Join(constant.GetProjectDir(), constant.SettingsFileName)
+}
+
+// Default 返回内置的默认设置
+func Default() *Settings {
+	defaultValue := constant.EnvDefault.String()
+	return &Settings{
+		Database: DatabaseSettings{Path: constant.GetDBFilePath()},
+		Defaults: DefaultSettings{Project: defaultValue, Env: defaultValue, Module: defaultValue},
+		Log:      LogSettings{Path: filepath.Join(constant.GetProjectDir(), constant.LogFileName), Level: "info"},
+		Output:   OutputSettings{Format: "text"},
+	}
+}
+
+// Load 读取设置文件并与默认设置合并
+// path 为空时读取默认设置文件，该文件不存在时直接使用默认设置；显式指定的文件必须存在
+func Load(path string) (*Settings, error) {
+	s := Default()
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+	path = ExpandPath(path)
+
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return s, s.finish()
+		}
+		return nil, fmt.Errorf("cannot read settings file: %w", err)
+	}
+
+	meta, err := toml.DecodeFile(path, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid settings file %s: %w", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return nil, fmt.Errorf("unknown settings in %s: %s", path, strings.Join(keys, ", "))
+	}
+
+	// 相对路径以设置文件所在目录为基准
+	base := filepath.Dir(path)
+	s.Database.Path = resolvePath(base, s.Database.Path)
+	s.Database.KeyFile = resolvePath(base, s.Database.KeyFile)
+	s.Log.Path = resolvePath(base, s.Log.Path)
+	s.Hook.AllowFile = resolvePath(base, s.Hook.AllowFile)
+	return s, s.finish()
+}
+
+// finish 补全未设置的字段并校验取值
+func (s *Settings) finish() error {
+	defaults := Default()
+	if s.Database.Path == "" {
+		s.Database.Path = defaults.Database.Path
+	}
+	if s.Database.KeyFile == "" {
+		// 主密钥默认与数据库放在同一目录，便于按客户分开存放
+		s.Database.KeyFile = filepath.Join(filepath.Dir(s.Database.Path), constant.KeyFileName)
+	}
+	if s.Hook.AllowFile == "" {
+		s.Hook.AllowFile = filepath.Join(filepath.Dir(s.Database.Path), constant.AllowFileName)
+	}
+	if s.Defaults.Project == "" {
+		s.Defaults.Project = defaults.Defaults.Project
+	}
+	if s.Defaults.Env == "" {
+		s.Defaults.Env = defaults.Defaults.Env
+	}
+	if s.Defaults.Module == "" {
+		s.Defaults.Module = defaults.Defaults.Module
+	}
+	if s.Log.Path == "" {
+		s.Log.Path = defaults.Log.Path
+	}
+	if s.Log.Level == "" {
+		s.Log.Level = defaults.Log.Level
+	}
+	if s.Output.Format == "" {
+		s.Output.Format = defaults.Output.Format
+	}
+
+	switch s.Log.Level {
+	case "debug", "info", "warning", "error":
+	default:
+		return fmt.Errorf("invalid log level %q, use debug, info, warning or error", s.Log.Level)
+	}
+	switch s.Output.Format {
+	case "text", "json", "yaml", "table", "csv":
+	default:
+		return fmt.Errorf("invalid output format %q, use text, json, yaml, table or csv", s.Output.Format)
+	}
+	for project, patterns := range s.Promote.Ignore {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid promote ignore pattern %q for project %s: %w", pattern, project, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ExpandPath 展开路径开头的 ~
+func ExpandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+func resolvePath(base, path string) string {
+	if path == "" {
+		return ""
+	}
+	path = ExpandPath(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	return path
+}
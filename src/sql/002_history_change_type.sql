@@ -0,0 +1,137 @@
+-- ============================================================================
+-- 迁移 002：历史表记录变更类型
+-- change_type 区分一条历史记录是由更新（update）还是删除（delete）产生的
+-- ============================================================================
+ALTER TABLE config_history ADD COLUMN change_type VARCHAR(10); -- 变更类型（update/delete）
+
+-- ============================================================================
+-- 回填已有历史记录：若之后仍存在同一 created_time 的记录（主表或更晚的历史），
+-- 说明该配置项被继续更新而非删除
+-- ============================================================================
+UPDATE config_history
+SET
+    change_type = CASE
+        WHEN EXISTS (
+            SELECT 1 FROM config_master m
+            WHERE m.project IS config_history.project
+                AND m.env IS config_history.env
+                AND m.module IS config_history.module
+                AND m.config_key IS config_history.config_key
+                AND m.created_time IS config_history.created_time
+        )
+        OR EXISTS (
+            SELECT 1 FROM config_history h
+            WHERE h.project IS config_history.project
+                AND h.env IS config_history.env
+                AND h.module IS config_history.module
+                AND h.config_key IS config_history.config_key
+                AND h.id > config_history.id
+                AND h.created_time IS config_history.created_time
+        ) THEN 'update'
+        ELSE 'delete'
+    END
+WHERE
+    change_type IS NULL;
+
+DROP TRIGGER IF EXISTS config_update_history_trigger;
+
+DROP TRIGGER IF EXISTS config_delete_history_trigger;
+
+-- ============================================================================
+-- 触发器1：配置更新时，自动将旧记录存入历史表
+-- 捕获所有有意义的字段变更（排除仅 updated_time 自动刷新）
+-- ============================================================================
+CREATE TRIGGER config_update_history_trigger AFTER
+UPDATE ON config_master FOR EACH ROW WHEN OLD.config_value != NEW.config_value
+OR IFNULL (OLD.auto_alias, '') != IFNULL (NEW.auto_alias, '')
+OR IFNULL (OLD.config_alias, '') != IFNULL (NEW.config_alias, '')
+OR OLD.config_type != NEW.config_type
+OR IFNULL (OLD.description, '') != IFNULL (NEW.description, '')
+OR OLD.is_encrypted != NEW.is_encrypted
+OR OLD.sort_order != NEW.sort_order BEGIN
+INSERT INTO
+    config_history (
+        project,
+        env,
+        module,
+        config_key,
+        auto_alias,
+        config_alias,
+        config_value,
+        config_type,
+        description,
+        is_encrypted,
+        sort_order,
+        created_time,
+        updated_time,
+        version,
+        changed_by,
+        change_type
+    )
+VALUES
+    (
+        OLD.project,
+        OLD.env,
+        OLD.module,
+        OLD.config_key,
+        OLD.auto_alias,
+        OLD.config_alias,
+        OLD.config_value,
+        OLD.config_type,
+        OLD.description,
+        OLD.is_encrypted,
+        OLD.sort_order,
+        OLD.created_time,
+        OLD.updated_time,
+        DATETIME ('now'),
+        'system',
+        'update'
+    );
+
+END;
+
+-- ============================================================================
+-- 触发器2：配置删除时，自动将被删除的记录存入历史表
+-- 物理 DELETE 操作触发，确保删除前状态被完整归档
+-- ============================================================================
+CREATE TRIGGER config_delete_history_trigger BEFORE DELETE ON config_master FOR EACH ROW BEGIN
+INSERT INTO
+    config_history (
+        project,
+        env,
+        module,
+        config_key,
+        auto_alias,
+        config_alias,
+        config_value,
+        config_type,
+        description,
+        is_encrypted,
+        sort_order,
+        created_time,
+        updated_time,
+        version,
+        changed_by,
+        change_type
+    )
+VALUES
+    (
+        OLD.project,
+        OLD.env,
+        OLD.module,
+        OLD.config_key,
+        OLD.auto_alias,
+        OLD.config_alias,
+        OLD.config_value,
+        OLD.config_type,
+        OLD.description,
+        OLD.is_encrypted,
+        OLD.sort_order,
+        OLD.created_time,
+        OLD.updated_time,
+        DATETIME ('now'),
+        'system',
+        'delete'
+    );
+
+END;
\ No newline at end of file
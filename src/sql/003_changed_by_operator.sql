@@ -0,0 +1,112 @@
+-- ============================================================================
+-- 迁移 003：记录真实的操作者
+-- 主表新增 updated_by 保存最后一次修改者，历史触发器据此写入 changed_by
+-- ============================================================================
+ALTER TABLE config_master ADD COLUMN updated_by VARCHAR(50); -- 最后修改该配置项的操作者
+
+DROP TRIGGER IF EXISTS config_update_history_trigger;
+
+DROP TRIGGER IF EXISTS config_delete_history_trigger;
+
+-- ============================================================================
+-- 触发器1：配置更新时，自动将旧记录存入历史表
+-- 捕获所有有意义的字段变更（排除仅 updated_time、updated_by 刷新）
+-- changed_by 记录本次变更的操作者，即 NEW.updated_by
+-- WHEN 条件不能比较 updated_by：删除配置前会单独更新 updated_by 记录操作者，
+-- 比较它会让每次删除多出一条 update 历史
+-- ============================================================================
+CREATE TRIGGER config_update_history_trigger AFTER
+UPDATE ON config_master FOR EACH ROW WHEN OLD.config_value != NEW.config_value
+OR IFNULL (OLD.auto_alias, '') != IFNULL (NEW.auto_alias, '')
+OR IFNULL (OLD.config_alias, '') != IFNULL (NEW.config_alias, '')
+OR OLD.config_type != NEW.config_type
+OR IFNULL (OLD.description, '') != IFNULL (NEW.description, '')
+OR OLD.is_encrypted != NEW.is_encrypted
+OR OLD.sort_order != NEW.sort_order BEGIN
+INSERT INTO
+    config_history (
+        project,
+        env,
+        module,
+        config_key,
+        auto_alias,
+        config_alias,
+        config_value,
+        config_type,
+        description,
+        is_encrypted,
+        sort_order,
+        created_time,
+        updated_time,
+        version,
+        changed_by,
+        change_type
+    )
+VALUES
+    (
+        OLD.project,
+        OLD.env,
+        OLD.module,
+        OLD.config_key,
+        OLD.auto_alias,
+        OLD.config_alias,
+        OLD.config_value,
+        OLD.config_type,
+        OLD.description,
+        OLD.is_encrypted,
+        OLD.sort_order,
+        OLD.created_time,
+        OLD.updated_time,
+        DATETIME ('now'),
+        IFNULL (NEW.updated_by, 'system'),
+        'update'
+    );
+
+END;
+
+-- ============================================================================
+-- 触发器2：配置删除时，自动将被删除的记录存入历史表
+-- 物理 DELETE 操作触发，确保删除前状态被完整归档
+-- 删除前需先将 updated_by 更新为删除操作者
+-- ============================================================================
+CREATE TRIGGER config_delete_history_trigger BEFORE DELETE ON config_master FOR EACH ROW BEGIN
+INSERT INTO
+    config_history (
+        project,
+        env,
+        module,
+        config_key,
+        auto_alias,
+        config_alias,
+        config_value,
+        config_type,
+        description,
+        is_encrypted,
+        sort_order,
+        created_time,
+        updated_time,
+        version,
+        changed_by,
+        change_type
+    )
+VALUES
+    (
+        OLD.project,
+        OLD.env,
+        OLD.module,
+        OLD.config_key,
+        OLD.auto_alias,
+        OLD.config_alias,
+        OLD.config_value,
+        OLD.config_type,
+        OLD.description,
+        OLD.is_encrypted,
+        OLD.sort_order,
+        OLD.created_time,
+        OLD.updated_time,
+        DATETIME ('now'),
+        IFNULL (OLD.updated_by, 'system'),
+        'delete'
+    );
+
+END;
\ No newline at end of file
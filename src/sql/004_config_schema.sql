@@ -0,0 +1,23 @@
+-- ============================================================================
+-- 迁移 004：模块的声明式约束
+-- 每行声明某项目、模块中的一个配置键：各环境是否必须存在，以及值的类型与取值约束
+-- 由 dem schema apply 按 project + module 整体替换
+-- ============================================================================
+CREATE TABLE IF NOT EXISTS config_schema (
+    id INTEGER PRIMARY KEY AUTOINCREMENT, -- 主键ID，自动递增
+    project VARCHAR(100) NOT NULL, -- 项目标识
+    module VARCHAR(50) NOT NULL DEFAULT 'default', -- 模块标识
+    config_key VARCHAR(200) NOT NULL, -- 配置项键名
+    config_type VARCHAR(20) NOT NULL DEFAULT 'string', -- 值类型（string/number/boolean/json/yaml）
+    required INTEGER NOT NULL DEFAULT 1, -- 是否必须存在（0=可选，1=必须）
+    envs TEXT, -- 必须存在该键的环境，JSON 数组；为空表示所有环境
+    enum_values TEXT, -- 允许的取值，JSON 数组；为空表示不限制
+    pattern TEXT, -- 值必须匹配的正则表达式
+    min_value REAL, -- number 类型的最小值（含）
+    max_value REAL, -- number 类型的最大值（含）
+    description TEXT, -- 配置项的说明
+    created_time DATETIME DEFAULT CURRENT_TIMESTAMP, -- 记录创建时间
+    updated_by VARCHAR(50), -- 应用该约束的操作者
+    -- 唯一约束：同一项目、模块下每个键只有一条约束
+    UNIQUE (project, module, config_key)
+);